@@ -3,6 +3,7 @@ package xmlcomparator
 import (
 	"encoding/xml"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/aknopov/handymaps/bimap"
@@ -18,42 +19,180 @@ const (
 	DiffChildren
 	DiffChildrenOrder
 	ParseError
+	DiffComments
+	DiffProcInsts
+	DiffDoctype
+	DiffNamespaceDecls
+	DiffPrefixes
 )
 
 type XmlDiff interface {
 	DescribeDiff() string
 	GetType() DiffType
+	// Path of the node in the first sample or, if the node is absent there, in the second one
 	XmlPath() string
+	// Path of the node in the first sample; empty if the node is absent there.
+	// Added and removed children are reported on the parent with their own paths in `ChildrenDiff.Edits()`
+	XmlPath1() string
+	// Path of the node in the second sample; empty if the node is absent there
+	XmlPath2() string
+	// Source position of the discrepancy in the first sample
+	Position1() Position
+	// Source position of the discrepancy in the second sample
+	Position2() Position
+}
+
+// Discrepancy of node names, namespaces or texts
+type TextDiff interface {
+	XmlDiff
+	// Value in the first sample
+	Value1() string
+	// Value in the second sample
+	Value2() string
+	// Index of the text segment among children in the source of mixed content; -1 if the whole text differs
+	Segment() int
+}
+
+// Kind of change of an attribute or a child element
+type ChangeType int
+
+const (
+	Changed ChangeType = iota + 1 // present in both samples but differs
+	Added                         // present only in the second sample
+	Removed                       // present only in the first sample
+)
+
+// Discrepancy of a single attribute
+type AttributeDiff interface {
+	XmlDiff
+	// Whether the attribute was changed, added or removed
+	Change() ChangeType
+	// Attribute in the first sample; `nil` if absent there
+	Attr1() *xml.Attr
+	// Attribute in the second sample; `nil` if absent there
+	Attr2() *xml.Attr
+	// Value in the first sample; empty if the attribute is absent there
+	Value1() string
+	// Value in the second sample; empty if the attribute is absent there
+	Value2() string
+}
+
+// Discrepancy of children lists
+type ChildrenDiff interface {
+	XmlDiff
+	// Count of children in the first sample
+	Count1() int
+	// Count of children in the second sample
+	Count2() int
+	// Edit script - changed and removed children in the order of the first sample, then added ones in the order of the second sample
+	Edits() []ChildEdit
+}
+
+// Discrepancy of children order
+type OrderDiff interface {
+	XmlDiff
+	// Count of children
+	Count() int
+	// Minimal set of children that changed their positions
+	Moves() []ChildMove
+}
+
+// Edit operation on a child element
+type ChildEdit struct {
+	Change ChangeType
+	Name   string // name of the element with the match key, if any
+	Index1 int    // index among siblings in the first sample source; -1 if the element is absent there
+	Index2 int    // index among siblings in the second sample source; -1 if the element is absent there
+	Path1  string // path of the element in the first sample; empty if the element is absent there
+	Path2  string // path of the element in the second sample; empty if the element is absent there
+	// Source position of the element in the first sample; zero if the element is absent there
+	Position1 Position
+	// Source position of the element in the second sample; zero if the element is absent there
+	Position2 Position
+}
+
+// Child element that changed its position among siblings
+type ChildMove struct {
+	Name string
+	From int // index in the first sample
+	To   int // index in the second sample
+}
+
+// Location of the discrepancy in both samples
+type diffLocation struct {
+	xmlPath1 string
+	xmlPath2 string
+	pos1     Position
+	pos2     Position
 }
 
 type parserError struct {
+	diffLocation
 	text string
 }
 
 type textualDiff struct {
-	diffType DiffType
-	text1    string
-	text2    string
-	xmlPath  string
+	diffLocation
+	diffType    DiffType
+	text1       string
+	text2       string
+	segment     int    // index of the text segment in mixed content; -1 for the whole text
+	segmentDesc string // position of the text segment among children
 }
 
 type attributeDiff struct {
-	diffs   []diffT[xml.Attr]
-	len1    int
-	len2    int
-	xmlPath string
+	diffLocation
+	attr1 *xml.Attr // nil if absent in the first sample
+	attr2 *xml.Attr // nil if absent in the second sample
 }
 
 type orderDiff struct {
-	len     int
-	xmlPath string
+	diffLocation
+	len   int
+	moves []ChildMove
 }
 
 type childrenDiff struct {
+	diffLocation
 	diffs   []diffT[parseNode]
+	changed []ChildEdit
+	namer   func(*parseNode) string
+	style   PathStyle
 	len1    int
 	len2    int
-	xmlPath string
+}
+
+// ------------
+
+func (loc diffLocation) XmlPath() string {
+	if loc.xmlPath1 == "" {
+		return loc.xmlPath2
+	}
+	return loc.xmlPath1
+}
+
+func (loc diffLocation) XmlPath1() string {
+	return loc.xmlPath1
+}
+
+func (loc diffLocation) XmlPath2() string {
+	return loc.xmlPath2
+}
+
+// Describes paths for the message - both of them only when they differ.
+func (loc diffLocation) describePaths() string {
+	if loc.xmlPath1 == "" || loc.xmlPath2 == "" || loc.xmlPath1 == loc.xmlPath2 {
+		return fmt.Sprintf("path='%s'", loc.XmlPath())
+	}
+	return fmt.Sprintf("path1='%s', path2='%s'", loc.xmlPath1, loc.xmlPath2)
+}
+
+func (loc diffLocation) Position1() Position {
+	return loc.pos1
+}
+
+func (loc diffLocation) Position2() Position {
+	return loc.pos2
 }
 
 // ------------
@@ -66,120 +205,205 @@ func (err parserError) GetType() DiffType {
 	return ParseError
 }
 
-func (err parserError) XmlPath() string {
-	return ""
+// ------------
+
+func createTextDiff(diffType DiffType, text1 string, text2 string, loc diffLocation) *textualDiff {
+	return &textualDiff{diffLocation: loc, diffType: diffType, text1: text1, text2: text2, segment: -1}
 }
 
-// ------------
+// Creates difference of text segments in mixed content.
+//   - segment - index of the segment - 0 for the text before the first child
+//   - segmentDesc - position of the segment among children, like "between b[0] and c[1]"
+func createSegmentDiff(text1 string, text2 string, segment int, segmentDesc string, loc diffLocation) *textualDiff {
+	return &textualDiff{diffLocation: loc, diffType: DiffContent, text1: text1, text2: text2, segment: segment, segmentDesc: segmentDesc}
+}
 
-func createTextDiff(diffType DiffType, text1 string, text2 string, xmlPath string) *textualDiff {
-	return &textualDiff{diffType: diffType, text1: text1, text2: text2, xmlPath: xmlPath}
+// Message prefixes of textual differences by type
+var textDiffDescriptions = map[DiffType]string{
+	DiffName:           "Node names differ",
+	DiffSpace:          "Node namespaces differ",
+	DiffContent:        "Node texts differ",
+	DiffComments:       "Comments differ",
+	DiffProcInsts:      "Processing instructions differ",
+	DiffDoctype:        "Document types differ",
+	DiffNamespaceDecls: "Namespace declarations differ",
+	DiffPrefixes:       "Namespace prefixes differ",
 }
 
 func (diff textualDiff) DescribeDiff() string {
-	switch diff.diffType {
-	case DiffName:
-		return fmt.Sprintf("Node names differ: '%s' vs '%s', path='%s'", diff.text1, diff.text2, diff.xmlPath)
-	case DiffSpace:
-		return fmt.Sprintf("Node namespaces differ: '%s' vs '%s', path='%s'", diff.text1, diff.text2, diff.xmlPath)
-	case DiffContent:
-		return fmt.Sprintf("Node texts differ: '%s' vs '%s', path='%s'", diff.text1, diff.text2, diff.xmlPath)
-	default:
-		panic("Unexpected textual diff type")
+	description, ok := textDiffDescriptions[diff.diffType]
+	if !ok {
+		description = "Nodes differ"
+	}
+	if diff.segment >= 0 {
+		return fmt.Sprintf("%s: '%s' vs '%s', segment='%s', %s", description, diff.text1, diff.text2, diff.segmentDesc, diff.describePaths())
 	}
+	return fmt.Sprintf("%s: '%s' vs '%s', %s", description, diff.text1, diff.text2, diff.describePaths())
 }
 
 func (diff textualDiff) GetType() DiffType {
 	return diff.diffType
 }
 
-func (diff textualDiff) XmlPath() string {
-	return diff.xmlPath
+func (diff textualDiff) Value1() string {
+	return diff.text1
+}
+
+func (diff textualDiff) Value2() string {
+	return diff.text2
+}
+
+func (diff textualDiff) Segment() int {
+	return diff.segment
+}
+
+func (diff textualDiff) diffValues() []string {
+	return []string{diff.text1, diff.text2}
 }
 
 // ------------
 
-func createAttributeDiff(diffs []diffT[xml.Attr], len1 int, len2 int, xmlPath string) *attributeDiff {
-	return &attributeDiff{diffs: diffs, len1: len1, len2: len2, xmlPath: xmlPath}
+// Creates attribute difference - either values differ or the attribute is absent in one of samples.
+func createAttributeDiff(attr1 *xml.Attr, attr2 *xml.Attr, loc diffLocation) *attributeDiff {
+	return &attributeDiff{diffLocation: loc, attr1: attr1, attr2: attr2}
 }
 
 func (diff attributeDiff) DescribeDiff() string {
-	matchingdMap := createMatchingElementsMap(diff.diffs, attrName)
+	var sDiff string
+	switch {
+	case diff.attr1 == nil && diff.attr2 == nil:
+		return ""
+	case diff.attr2 == nil:
+		sDiff = fmt.Sprintf("Attribute is absent in the second sample: '%s=%s'", attrName(diff.attr1), diff.attr1.Value)
+	case diff.attr1 == nil:
+		sDiff = fmt.Sprintf("Attribute is absent in the first sample: '%s=%s'", attrName(diff.attr2), diff.attr2.Value)
+	default:
+		sDiff = fmt.Sprintf("Attribute values differ: '%s' vs '%s'", diff.attr1.Value, diff.attr2.Value)
+	}
 
-	unmatchedDiffs := make([]diffT[xml.Attr], 0, len(diff.diffs)/2)
-	for i := 0; i < len(diff.diffs); i++ {
-		if !matchingdMap.ContainsValue(i) && !matchingdMap.ContainsKey(i) {
-			unmatchedDiffs = append(unmatchedDiffs, diff.diffs[i])
-		}
+	if space := diff.attrSpace(); space != "" {
+		sDiff += fmt.Sprintf(", namespace='%s'", space)
 	}
+	return fmt.Sprintf("%s, %s", sDiff, diff.describePaths())
+}
 
-	// Log first mismatched attributes...
-	var sDiffs string
-	if len(unmatchedDiffs) > 0 {
-		sDiffs = fmt.Sprintf("counts %d vs %d: %s", diff.len1, diff.len2, extractNames(unmatchedDiffs, attrName))
+func (diff attributeDiff) GetType() DiffType {
+	return DiffAttributes
+}
+
+func (diff attributeDiff) Change() ChangeType {
+	switch {
+	case diff.attr1 == nil:
+		return Added
+	case diff.attr2 == nil:
+		return Removed
+	default:
+		return Changed
 	}
-	// ... then matching with different content
-	it := matchingdMap.Iterator()
-	for it.HasNext() {
-		i, j := it.Next()
-		attr1 := &diff.diffs[i].e
-		attr2 := &diff.diffs[j].e
-		if len(sDiffs) != 0 {
-			sDiffs += ", "
-		}
-		sDiffs += fmt.Sprintf("'%s=%s' vs '%s=%s'", attrName(attr1), attr1.Value, attrName(attr2), attr2.Value)
+}
+
+func (diff attributeDiff) Attr1() *xml.Attr {
+	return diff.attr1
+}
+
+func (diff attributeDiff) Attr2() *xml.Attr {
+	return diff.attr2
+}
+
+func (diff attributeDiff) Value1() string {
+	if diff.attr1 != nil {
+		return diff.attr1.Value
 	}
+	return ""
+}
 
-	return fmt.Sprintf("Attributes differ: %s, path='%s'", sDiffs, diff.xmlPath)
+func (diff attributeDiff) Value2() string {
+	if diff.attr2 != nil {
+		return diff.attr2.Value
+	}
+	return ""
 }
 
-func (diff attributeDiff) GetType() DiffType {
-	return DiffAttributes
+func (diff attributeDiff) attr() *xml.Attr {
+	if diff.attr1 != nil {
+		return diff.attr1
+	}
+	return diff.attr2
+}
+
+func (diff attributeDiff) attrSpace() string {
+	if attr := diff.attr(); attr != nil {
+		return attrSpace(attr)
+	}
+	return ""
+}
+
+func (diff attributeDiff) attrNames() []string {
+	if attr := diff.attr(); attr != nil {
+		return []string{attrName(attr)}
+	}
+	return []string{}
 }
 
-func (diff attributeDiff) XmlPath() string {
-	return diff.xmlPath
+func (diff attributeDiff) diffValues() []string {
+	values := make([]string, 0, 2)
+	if diff.attr1 != nil {
+		values = append(values, diff.attr1.Value)
+	}
+	if diff.attr2 != nil {
+		values = append(values, diff.attr2.Value)
+	}
+	return values
 }
 
 // ------------
 
-func createOrderDiff(len int, xmlPath string) *orderDiff {
-	return &orderDiff{len: len, xmlPath: xmlPath}
+func createOrderDiff(len int, moves []ChildMove, loc diffLocation) *orderDiff {
+	return &orderDiff{diffLocation: loc, len: len, moves: moves}
 }
 
 func (diff orderDiff) DescribeDiff() string {
-	return fmt.Sprintf("Children order differ for %d nodes, path='%s'", diff.len, diff.xmlPath)
+	if len(diff.moves) == 0 {
+		return fmt.Sprintf("Children order differ for %d nodes, %s", diff.len, diff.describePaths())
+	}
+
+	moves := make([]string, len(diff.moves))
+	for i := range diff.moves {
+		moves[i] = fmt.Sprintf("%s[%d]->%d", diff.moves[i].Name, diff.moves[i].From, diff.moves[i].To)
+	}
+	return fmt.Sprintf("Children order differ for %d nodes: %s, %s", diff.len, strings.Join(moves, ", "), diff.describePaths())
 }
 
 func (diff orderDiff) GetType() DiffType {
 	return DiffChildrenOrder
 }
 
-func (diff orderDiff) XmlPath() string {
-	return diff.xmlPath
+func (diff orderDiff) Count() int {
+	return diff.len
+}
+
+func (diff orderDiff) Moves() []ChildMove {
+	return diff.moves
 }
 
 // ------------
 
-func createChildrenDiff(diffs []diffT[parseNode], len1 int, len2 int, xmlPath string) *childrenDiff {
-	return &childrenDiff{diffs: diffs, len1: len1, len2: len2, xmlPath: xmlPath}
+// Creates children difference.
+//   - diffs - added and deleted children
+//   - changed - pairs of modified children that are further compared
+//   - namer - provides names of children for reporting
+func createChildrenDiff(diffs []diffT[parseNode], changed []ChildEdit, namer func(*parseNode) string, style PathStyle,
+	len1 int, len2 int, loc diffLocation) *childrenDiff {
+	return &childrenDiff{diffLocation: loc, diffs: diffs, changed: changed, namer: namer, style: style, len1: len1, len2: len2}
 }
 
 func (diff childrenDiff) DescribeDiff() string {
-	// return fmt.Sprintf("Children differ: counts %d vs %d, path='%s'", diff.Len1, diff.Len2, diff.XmlPath)
-	matchingdMap := createMatchingElementsMap(diff.diffs, nodeName)
-
-	unmatchedDiffs := make([]diffT[parseNode], 0, len(diff.diffs)/2)
-	for i := 0; i < len(diff.diffs); i++ {
-		if !matchingdMap.ContainsValue(i) && !matchingdMap.ContainsKey(i) {
-			unmatchedDiffs = append(unmatchedDiffs, diff.diffs[i])
-		}
-	}
+	unmatchedDiffs := diff.unmatchedDiffs()
 
 	// Log first message for this node
 	if len(unmatchedDiffs) > 0 {
-		return fmt.Sprintf("Children differ: counts %d vs %d: %s, path='%s'", diff.len1, diff.len2,
-			extractNames(unmatchedDiffs, nodeName), diff.xmlPath)
+		return fmt.Sprintf("Children differ: counts %d vs %d: %s, %s", diff.len1, diff.len2,
+			extractNames(unmatchedDiffs, diff.namer), diff.describePaths())
 	}
 	return ""
 }
@@ -188,19 +412,70 @@ func (diff childrenDiff) GetType() DiffType {
 	return DiffChildren
 }
 
-func (diff childrenDiff) XmlPath() string {
-	return diff.xmlPath
+func (diff childrenDiff) Count1() int {
+	return diff.len1
+}
+
+func (diff childrenDiff) Count2() int {
+	return diff.len2
+}
+
+func (diff childrenDiff) Edits() []ChildEdit {
+	edits := make([]ChildEdit, 0, len(diff.diffs))
+	edits = append(edits, diff.changed...)
+	for _, d := range diff.unmatchedDiffs() {
+		if d.t == diffDelete {
+			edits = append(edits, ChildEdit{Change: Removed, Name: diff.namer(&d.e), Index1: d.aIdx, Index2: -1,
+				Path1: d.e.pathEx(diff.style), Position1: d.e.Pos})
+		} else {
+			edits = append(edits, ChildEdit{Change: Added, Name: diff.namer(&d.e), Index1: -1, Index2: d.aIdx,
+				Path2: d.e.pathEx(diff.style), Position2: d.e.Pos})
+		}
+	}
+
+	sort.SliceStable(edits, func(i, j int) bool {
+		if (edits[i].Index1 < 0) != (edits[j].Index1 < 0) {
+			return edits[j].Index1 < 0
+		}
+		if edits[i].Index1 != edits[j].Index1 {
+			return edits[i].Index1 < edits[j].Index1
+		}
+		return edits[i].Index2 < edits[j].Index2
+	})
+	return edits
+}
+
+// Provides added and deleted children that are not paired as changed ones.
+func (diff childrenDiff) unmatchedDiffs() []diffT[parseNode] {
+	unmatchedDiffs := make([]diffT[parseNode], 0, len(diff.diffs))
+	for i := range diff.diffs {
+		if !diff.isChanged(&diff.diffs[i]) {
+			unmatchedDiffs = append(unmatchedDiffs, diff.diffs[i])
+		}
+	}
+	return unmatchedDiffs
+}
+
+func (diff childrenDiff) isChanged(d *diffT[parseNode]) bool {
+	for i := range diff.changed {
+		if d.t == diffDelete && diff.changed[i].Index1 == d.aIdx || d.t == diffAdd && diff.changed[i].Index2 == d.aIdx {
+			return true
+		}
+	}
+	return false
 }
 
 // ------------
 
 // Matches nodes in diff list there were modified and can be further compared.
 // Matching diffs should have complementary edit operation (add/delete) and the same element name.
+//
+// Returns: map of indices of deleted elements to indices of added ones
 func createMatchingElementsMap[T any](diffs []diffT[T], namer func(*T) string) *bimap.BiMap[int, int] {
 	modifiedMap := bimap.NewBiMapEx[int, int](len(diffs) / 2)
 
 	for i := 0; i < len(diffs); i++ {
-		if modifiedMap.ContainsValue(i) {
+		if isPaired(modifiedMap, i) {
 			continue
 		}
 
@@ -210,12 +485,16 @@ func createMatchingElementsMap[T any](diffs []diffT[T], namer func(*T) string) *
 		}
 
 		for j := i + 1; j < len(diffs); j++ {
-			if modifiedMap.ContainsValue(j) {
+			if isPaired(modifiedMap, j) {
 				continue
 			}
 
 			if diffs[j].t == complementDiff && namer(&diffs[i].e) == namer(&diffs[j].e) {
-				modifiedMap.Put(i, j)
+				if complementDiff == diffAdd {
+					modifiedMap.Put(i, j)
+				} else {
+					modifiedMap.Put(j, i)
+				}
 				break
 			}
 		}
@@ -224,6 +503,11 @@ func createMatchingElementsMap[T any](diffs []diffT[T], namer func(*T) string) *
 	return modifiedMap
 }
 
+// Checks if the diff with the index is already paired either as a deleted or an added element.
+func isPaired(modifiedMap *bimap.BiMap[int, int], idx int) bool {
+	return modifiedMap.ContainsKey(idx) || modifiedMap.ContainsValue(idx)
+}
+
 func extractNames[T any](mismatchedDiffs []diffT[T], namer func(*T) string) string {
 	names := make([]string, 0, len(mismatchedDiffs))
 
@@ -235,28 +519,30 @@ func extractNames[T any](mismatchedDiffs []diffT[T], namer func(*T) string) stri
 	return strings.Join(names, ", ")
 }
 
-// Extracts names with run-length "compression"
+// Extracts names with run-length "compression" of adjacent elements with the same name
 func extractNamesByType[T any](mismatchedDiffs []diffT[T], diffType editType, sign string, namer func(*T) string) []string {
 	names := make([]string, 0)
-	var startIdx, dataIdx int
+	var dataIdx, count int
 	prevName := ""
 
 	for i := range mismatchedDiffs {
-		if mismatchedDiffs[i].t == diffType {
-			if prevName == "" {
-				dataIdx = mismatchedDiffs[i].aIdx
-				prevName = namer(&mismatchedDiffs[i].e)
-				startIdx = i
-			}
-		} else {
-			if prevName != "" {
-				names = append(names, fmt.Sprintf("%s[%d]:%s%d", prevName, dataIdx, sign, i-startIdx))
-			}
+		if mismatchedDiffs[i].t == diffType && prevName != "" &&
+			namer(&mismatchedDiffs[i].e) == prevName && mismatchedDiffs[i].aIdx == dataIdx+count {
+			count++
+			continue
+		}
+		if prevName != "" {
+			names = append(names, fmt.Sprintf("%s[%d]:%s%d", prevName, dataIdx, sign, count))
 			prevName = ""
 		}
+		if mismatchedDiffs[i].t == diffType {
+			dataIdx = mismatchedDiffs[i].aIdx
+			prevName = namer(&mismatchedDiffs[i].e)
+			count = 1
+		}
 	}
 	if prevName != "" {
-		names = append(names, fmt.Sprintf("%s[%d]:%s%d", prevName, dataIdx, sign, len(mismatchedDiffs)-startIdx))
+		names = append(names, fmt.Sprintf("%s[%d]:%s%d", prevName, dataIdx, sign, count))
 	}
 
 	return names
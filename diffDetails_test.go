@@ -10,37 +10,51 @@ import (
 func TestCreators(t *testing.T) {
 	assertT := assert.New(t)
 
-	textDiff := createTextDiff(DiffName, "a", "b", "/")
+	textDiff := createTextDiff(DiffName, "a", "b", diffLocation{xmlPath1: "/"})
 	assertT.IsType(&textualDiff{}, textDiff)
 
-	attribDiff := createAttributeDiff([]diffT[xml.Attr]{}, 0, 0, "/")
+	attribDiff := createAttributeDiff(nil, nil, diffLocation{xmlPath1: "/"})
 	assertT.IsType(&attributeDiff{}, attribDiff)
 
-	ordrDiff := createOrderDiff(0, "/")
+	ordrDiff := createOrderDiff(0, []ChildMove{}, diffLocation{xmlPath1: "/"})
 	assertT.IsType(&orderDiff{}, ordrDiff)
 
-	childDiff := createChildrenDiff([]diffT[parseNode]{}, 0, 0, "/")
+	childDiff := createChildrenDiff([]diffT[parseNode]{}, []ChildEdit{}, nodeName, CompactPathStyle, 0, 0, diffLocation{xmlPath1: "/"})
 	assertT.IsType(&childrenDiff{}, childDiff)
 }
 
 func TestDescribeDiff(t *testing.T) {
 	assertT := assert.New(t)
 
-	parseError := parserError{"some error"}
+	parseError := parserError{text: "some error"}
 	assertT.Equal("some error", parseError.DescribeDiff())
 
-	textDiff := createTextDiff(DiffName, "a", "b", "/")
+	textDiff := createTextDiff(DiffName, "a", "b", diffLocation{xmlPath1: "/"})
 	assertT.Equal("Node names differ: 'a' vs 'b', path='/'", textDiff.DescribeDiff())
-
-	diffs1 := []diffT[xml.Attr]{{e: xml.Attr{Name: xml.Name{Space: "spc", Local: "name"}, Value: "val"}, t: diffSame}}
-	attribDiff := createAttributeDiff(diffs1, 0, 0, "/")
-	assertT.Equal("Attributes differ: counts 0 vs 0: , path='/'", attribDiff.DescribeDiff())
-
-	ordrDiff := createOrderDiff(1, "/")
+	textDiff = createTextDiff(DiffDoctype, "a", "b", diffLocation{xmlPath1: "/"})
+	assertT.Equal("Document types differ: 'a' vs 'b', path='/'", textDiff.DescribeDiff())
+	textDiff = createSegmentDiff("a", "b", 1, "between b[0] and c[1]", diffLocation{xmlPath1: "/"})
+	assertT.Equal("Node texts differ: 'a' vs 'b', segment='between b[0] and c[1]', path='/'", textDiff.DescribeDiff())
+
+	attr1 := &xml.Attr{Name: xml.Name{Space: "spc", Local: "name"}, Value: "val"}
+	attr2 := &xml.Attr{Name: xml.Name{Local: "name"}, Value: "val2"}
+	attribDiff := createAttributeDiff(attr1, attr2, diffLocation{xmlPath1: "/a/@name", xmlPath2: "/a/@name"})
+	assertT.Equal("Attribute values differ: 'val' vs 'val2', namespace='spc', path='/a/@name'", attribDiff.DescribeDiff())
+	attribDiff = createAttributeDiff(attr1, nil, diffLocation{xmlPath1: "/a/@name"})
+	assertT.Equal("Attribute is absent in the second sample: 'name=val', namespace='spc', path='/a/@name'", attribDiff.DescribeDiff())
+	attribDiff = createAttributeDiff(nil, attr2, diffLocation{xmlPath2: "/a/@name"})
+	assertT.Equal("Attribute is absent in the first sample: 'name=val2', path='/a/@name'", attribDiff.DescribeDiff())
+	attribDiff = createAttributeDiff(nil, nil, diffLocation{xmlPath1: "/a"})
+	assertT.Equal("", attribDiff.DescribeDiff())
+
+	ordrDiff := createOrderDiff(1, []ChildMove{}, diffLocation{xmlPath1: "/"})
 	assertT.Equal("Children order differ for 1 nodes, path='/'", ordrDiff.DescribeDiff())
 
+	ordrDiff = createOrderDiff(3, []ChildMove{{Name: "b", From: 0, To: 2}, {Name: "c", From: 2, To: 1}}, diffLocation{xmlPath1: "/a"})
+	assertT.Equal("Children order differ for 3 nodes: b[0]->2, c[2]->1, path='/a'", ordrDiff.DescribeDiff())
+
 	diffs2 := []diffT[parseNode]{{e: parseNode{XMLName: xml.Name{Space: "spc", Local: "name"}}, t: diffSame}}
-	childDiff := createChildrenDiff(diffs2, 0, 0, "/")
+	childDiff := createChildrenDiff(diffs2, []ChildEdit{}, nodeName, CompactPathStyle, 0, 0, diffLocation{xmlPath1: "/"})
 	assertT.Equal("Children differ: counts 0 vs 0: , path='/'", childDiff.DescribeDiff())
 }
 
@@ -51,13 +65,16 @@ func TestGetType(t *testing.T) {
 		diff  XmlDiff
 		dType DiffType
 	}{
-		{&parserError{"some error"}, ParseError},
-		{createTextDiff(DiffName, "a", "b", "/"), DiffName},
-		{createTextDiff(DiffSpace, "a", "b", "/"), DiffSpace},
-		{createTextDiff(DiffContent, "a", "b", "/"), DiffContent},
-		{createAttributeDiff(make([]diffT[xml.Attr], 0), 0, 0, "/"), DiffAttributes},
-		{createOrderDiff(0, "/"), DiffChildrenOrder},
-		{createChildrenDiff(make([]diffT[parseNode], 0), 0, 0, "/"), DiffChildren},
+		{&parserError{text: "some error"}, ParseError},
+		{createTextDiff(DiffName, "a", "b", diffLocation{xmlPath1: "/"}), DiffName},
+		{createTextDiff(DiffSpace, "a", "b", diffLocation{xmlPath1: "/"}), DiffSpace},
+		{createTextDiff(DiffContent, "a", "b", diffLocation{xmlPath1: "/"}), DiffContent},
+		{createTextDiff(DiffComments, "a", "b", diffLocation{xmlPath1: "/"}), DiffComments},
+		{createTextDiff(DiffProcInsts, "a", "b", diffLocation{xmlPath1: "/"}), DiffProcInsts},
+		{createTextDiff(DiffDoctype, "a", "b", diffLocation{xmlPath1: "/"}), DiffDoctype},
+		{createAttributeDiff(nil, nil, diffLocation{xmlPath1: "/"}), DiffAttributes},
+		{createOrderDiff(0, []ChildMove{}, diffLocation{xmlPath1: "/"}), DiffChildrenOrder},
+		{createChildrenDiff(make([]diffT[parseNode], 0), []ChildEdit{}, nodeName, CompactPathStyle, 0, 0, diffLocation{xmlPath1: "/"}), DiffChildren},
 	}
 
 	for _, tt := range tests {
@@ -65,9 +82,69 @@ func TestGetType(t *testing.T) {
 	}
 }
 
+func TestTypedAccessors(t *testing.T) {
+	assertT := assert.New(t)
+
+	var diff XmlDiff = createTextDiff(DiffContent, "a", "b", diffLocation{xmlPath1: "/"})
+	textDiff, ok := diff.(TextDiff)
+	assertT.True(ok)
+	assertT.Equal("a", textDiff.Value1())
+	assertT.Equal("b", textDiff.Value2())
+	assertT.Equal(-1, textDiff.Segment())
+	assertT.Equal(2, createSegmentDiff("a", "b", 2, "after c[1]", diffLocation{}).Segment())
+
+	attr := &xml.Attr{Name: xml.Name{Local: "name"}, Value: "val"}
+	diff = createAttributeDiff(attr, nil, diffLocation{xmlPath1: "/a/@name"})
+	attribDiff, ok := diff.(AttributeDiff)
+	assertT.True(ok)
+	assertT.Equal(Removed, attribDiff.Change())
+	assertT.Equal(attr, attribDiff.Attr1())
+	assertT.Nil(attribDiff.Attr2())
+	assertT.Equal("val", attribDiff.Value1())
+	assertT.Equal("", attribDiff.Value2())
+	assertT.Equal(Added, createAttributeDiff(nil, attr, diffLocation{}).Change())
+	assertT.Equal(Changed, createAttributeDiff(attr, attr, diffLocation{}).Change())
+
+	diff = createOrderDiff(3, []ChildMove{{Name: "b", From: 0, To: 2}}, diffLocation{xmlPath1: "/a"})
+	ordrDiff, ok := diff.(OrderDiff)
+	assertT.True(ok)
+	assertT.Equal(3, ordrDiff.Count())
+	assertT.Equal([]ChildMove{{Name: "b", From: 0, To: 2}}, ordrDiff.Moves())
+
+	diffs := []diffT[parseNode]{
+		{e: parseNode{XMLName: xml.Name{Local: "c"}}, t: diffAdd, aIdx: 2},
+		{e: parseNode{XMLName: xml.Name{Local: "b"}}, t: diffDelete, aIdx: 1},
+		{e: parseNode{XMLName: xml.Name{Local: "d"}}, t: diffDelete, aIdx: 0},
+	}
+	changed := []ChildEdit{{Change: Changed, Name: "b", Index1: 1, Index2: 0}}
+	diff = createChildrenDiff(diffs, changed, nodeName, CompactPathStyle, 2, 3, diffLocation{xmlPath1: "/a"})
+	childDiff, ok := diff.(ChildrenDiff)
+	assertT.True(ok)
+	assertT.Equal(2, childDiff.Count1())
+	assertT.Equal(3, childDiff.Count2())
+	assertT.Equal([]ChildEdit{
+		{Change: Removed, Name: "d", Index1: 0, Index2: -1, Path1: "/d"},
+		{Change: Changed, Name: "b", Index1: 1, Index2: 0},
+		{Change: Added, Name: "c", Index1: -1, Index2: 2, Path2: "/c"},
+	}, childDiff.Edits())
+	assertT.Equal("Children differ: counts 2 vs 3: d[0]:+1, c[2]:-1, path='/a'", childDiff.DescribeDiff())
+}
+
 func TestInvalidDescribeDiff(t *testing.T) {
 	assertT := assert.New(t)
 
-	invalidDiff := createTextDiff(DiffChildren, "a", "b", "/")
-	assertT.Panics(func() { invalidDiff.DescribeDiff() })
+	invalidDiff := createTextDiff(DiffChildren, "a", "b", diffLocation{xmlPath1: "/"})
+	assertT.NotPanics(func() { invalidDiff.DescribeDiff() })
+	assertT.Equal("Nodes differ: 'a' vs 'b', path='/'", invalidDiff.DescribeDiff())
+}
+
+func TestExtractNames(t *testing.T) {
+	assertT := assert.New(t)
+
+	namer := func(r *rune) string { return string(*r) }
+	diffs := []diffT[rune]{
+		{e: 'c', t: diffDelete, aIdx: 0}, {e: 'c', t: diffDelete, aIdx: 1}, {e: 'd', t: diffDelete, aIdx: 2},
+		{e: 'c', t: diffDelete, aIdx: 5}, {e: 'e', t: diffAdd, aIdx: 1}, {e: 'e', t: diffAdd, aIdx: 2},
+	}
+	assertT.Equal("c[0]:+2, d[2]:+1, c[5]:+1, e[1]:-2", extractNames(diffs, namer))
 }
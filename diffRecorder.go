@@ -24,6 +24,7 @@ type DiffRecorder interface {
 // Discrepancy messages collected while walking the trees.
 type diffRecorder struct {
 	ignoredDiscrepancies []*regexp.Regexp
+	ignoreRules          []*ignoreRule
 	diffs                []XmlDiff
 	messages             []string
 	namespaces           map[keyValue]void
@@ -38,14 +39,12 @@ func (recorder diffRecorder) GetMessages() []string {
 }
 
 // Creates an instance of DiffRecorder.
-func createDiffRecorder(ignoredDiscrepancies []string) *diffRecorder {
-	regexes := make([]*regexp.Regexp, len(ignoredDiscrepancies))
-	for i := range ignoredDiscrepancies {
-		regexes[i] = regexp.MustCompile(ignoredDiscrepancies[i])
-	}
-
+//   - ignoredDiscrepancies - compiled regular expressions for ignored discrepancies
+//   - ignoreRules - compiled rules for ignored discrepancies
+func createDiffRecorder(ignoredDiscrepancies []*regexp.Regexp, ignoreRules []*ignoreRule) *diffRecorder {
 	return &diffRecorder{
-		ignoredDiscrepancies: regexes,
+		ignoredDiscrepancies: ignoredDiscrepancies,
+		ignoreRules:          ignoreRules,
 		diffs:                make([]XmlDiff, 0),
 		messages:             make([]string, 0),
 		namespaces:           make(map[keyValue]void),
@@ -54,7 +53,7 @@ func createDiffRecorder(ignoredDiscrepancies []string) *diffRecorder {
 
 func (recorder *diffRecorder) addDiff(diff XmlDiff) {
 	msg := diff.DescribeDiff()
-	if len(msg) != 0 && !recorder.isIgnored(msg) {
+	if len(msg) != 0 && !recorder.isIgnored(msg) && !recorder.isRuleIgnored(diff) {
 		recorder.diffs = append(recorder.diffs, diff)
 		recorder.messages = append(recorder.messages, msg)
 	}
@@ -69,6 +68,15 @@ func (recorder *diffRecorder) isIgnored(msg string) bool {
 	return false
 }
 
+func (recorder *diffRecorder) isRuleIgnored(diff XmlDiff) bool {
+	for _, rule := range recorder.ignoreRules {
+		if rule.matches(diff) {
+			return true
+		}
+	}
+	return false
+}
+
 func (recorder *diffRecorder) areNamespacesNew(space1 string, space2 string) bool {
 	aPair := keyValue{space1, space2}
 	if _, ok := recorder.namespaces[aPair]; ok {
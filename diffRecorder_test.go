@@ -1,6 +1,7 @@
 package xmlcomparator
 
 import (
+	"regexp"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -22,10 +23,26 @@ func (diff testDiff) XmlPath() string {
 	return "/a/b"
 }
 
+func (diff testDiff) XmlPath1() string {
+	return "/a/b"
+}
+
+func (diff testDiff) XmlPath2() string {
+	return "/a/b"
+}
+
+func (diff testDiff) Position1() Position {
+	return Position{}
+}
+
+func (diff testDiff) Position2() Position {
+	return Position{}
+}
+
 func TestKnownMessagesFiltering(t *testing.T) {
 	assertT := assert.New(t)
 
-	recorder := createDiffRecorder([]string{"^footer.*$"})
+	recorder := createDiffRecorder([]*regexp.Regexp{regexp.MustCompile("^footer.*$")}, []*ignoreRule{})
 	recorder.addDiff(testDiff{"header"})
 	recorder.addDiff(testDiff{"body"})
 	recorder.addDiff(testDiff{"footer"})
@@ -37,7 +54,7 @@ func TestKnownMessagesFiltering(t *testing.T) {
 func TestAreNamespacesNew(t *testing.T) {
 	assertT := assert.New(t)
 
-	recorder := createDiffRecorder([]string{"^footer.*$"})
+	recorder := createDiffRecorder([]*regexp.Regexp{regexp.MustCompile("^footer.*$")}, []*ignoreRule{})
 
 	assertT.True(recorder.areNamespacesNew("space1", "space2"))
 	assertT.False(recorder.areNamespacesNew("space1", "space2"))
@@ -46,7 +63,7 @@ func TestAreNamespacesNew(t *testing.T) {
 func TestAccessToDetails(t *testing.T) {
 	assertT := assert.New(t)
 
-	recorder := createDiffRecorder([]string{})
+	recorder := createDiffRecorder([]*regexp.Regexp{}, []*ignoreRule{})
 	recorder.addDiff(testDiff{"header"})
 	recorder.addDiff(testDiff{"body"})
 
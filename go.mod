@@ -4,6 +4,8 @@ go 1.21
 
 require github.com/stretchr/testify v1.10.0
 
+require golang.org/x/text v0.14.0
+
 require (
 	github.com/aknopov/handymaps v0.0.2
 	github.com/davecgh/go-spew v1.1.1 // indirect
@@ -0,0 +1,171 @@
+package xmlcomparator
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Rule for ignoring discrepancies. Empty fields match any discrepancy.
+type IgnoreRule struct {
+	Type  DiffType // type of ignored discrepancies
+	Path  string   // path pattern of the discrepancy node, like "/envelope/header/**"
+	Attr  string   // name of the attribute or `*` for any attribute; matches only attribute discrepancies
+	Value string   // regular expression that all compared values should match
+}
+
+// Compiled ignore rule
+type ignoreRule struct {
+	diffType DiffType
+	path     *pathPattern
+	attr     string
+	value    *regexp.Regexp
+}
+
+// Discrepancy that concerns node attributes
+type attributesDiff interface {
+	// Names of the attributes that differ
+	attrNames() []string
+}
+
+// Discrepancy that concerns node values
+type valuesDiff interface {
+	// Values that differ
+	diffValues() []string
+}
+
+// Validates and compiles ignore rule.
+//   - index - index of the rule in the option arguments
+//   - rule - the rule
+//
+// Returns: compiled rule or `PatternError` for the invalid path or value pattern
+func compileIgnoreRule(index int, rule IgnoreRule) (*ignoreRule, error) {
+	compiled := &ignoreRule{diffType: rule.Type, attr: rule.Attr}
+
+	if rule.Path != "" {
+		pattern, err := compilePathPattern(rule.Path)
+		if err != nil {
+			return nil, &PatternError{Index: index, Pattern: rule.Path, Err: err}
+		}
+		compiled.path = pattern
+	}
+
+	if rule.Value != "" {
+		regex, err := regexp.Compile(rule.Value)
+		if err != nil {
+			return nil, &PatternError{Index: index, Pattern: rule.Value, Err: err}
+		}
+		compiled.value = regex
+	}
+
+	return compiled, nil
+}
+
+// Checks if the rule matches discrepancy.
+func (rule *ignoreRule) matches(diff XmlDiff) bool {
+	return rule.matchesType(diff) && rule.matchesPath(diff) && rule.matchesAttrs(diff) && rule.matchesValues(diff)
+}
+
+func (rule *ignoreRule) matchesType(diff XmlDiff) bool {
+	return rule.diffType == 0 || rule.diffType == diff.GetType()
+}
+
+func (rule *ignoreRule) matchesPath(diff XmlDiff) bool {
+	return rule.path == nil || rule.matchesNames(pathNames(diff.XmlPath())) ||
+		diff.XmlPath2() != "" && rule.matchesNames(pathNames(diff.XmlPath2()))
+}
+
+// Checks if the path pattern matches the names.
+// Rules with attributes also match the path of the owning element, like "/a/b" for "/a/b/@ts".
+func (rule *ignoreRule) matchesNames(names []string) bool {
+	if rule.path.matches(names) {
+		return true
+	}
+	last := len(names) - 1
+	return rule.attr != "" && last >= 0 && strings.HasPrefix(names[last], attrPrefix) && rule.path.matches(names[:last])
+}
+
+func (rule *ignoreRule) matchesAttrs(diff XmlDiff) bool {
+	if rule.attr == "" {
+		return true
+	}
+
+	attrDiff, ok := diff.(attributesDiff)
+	if !ok {
+		return false
+	}
+	names := attrDiff.attrNames()
+	for _, name := range names {
+		if rule.attr != anyName && rule.attr != name {
+			return false
+		}
+	}
+	return len(names) > 0
+}
+
+func (rule *ignoreRule) matchesValues(diff XmlDiff) bool {
+	if rule.value == nil {
+		return true
+	}
+
+	valDiff, ok := diff.(valuesDiff)
+	if !ok {
+		return false
+	}
+	values := valDiff.diffValues()
+	for _, value := range values {
+		if !rule.value.MatchString(value) {
+			return false
+		}
+	}
+	return len(values) > 0
+}
+
+// Splits XML path in any style to node names, dropping sibling indices and namespace prefixes.
+func pathNames(xmlPath string) []string {
+	names := make([]string, 0)
+	for _, step := range splitPath(xmlPath) {
+		prefix := ""
+		if strings.HasPrefix(step, attrPrefix) {
+			prefix, step = attrPrefix, step[len(attrPrefix):]
+		}
+
+		if strings.HasPrefix(step, localNameStep) {
+			step = step[len(localNameStep):]
+			step = step[:max(strings.IndexByte(step, '\''), 0)]
+		} else if idx := strings.IndexByte(step, '['); idx >= 0 {
+			step = step[:idx]
+		}
+		if idx := strings.IndexByte(step, ':'); idx >= 0 {
+			step = step[idx+1:]
+		}
+
+		if step != "" {
+			names = append(names, prefix+step)
+		}
+	}
+	return names
+}
+
+// Splits XML path by slashes that are not inside predicates.
+func splitPath(xmlPath string) []string {
+	steps := make([]string, 0)
+	depth, quote, start := 0, byte(0), 0
+	for i := 0; i < len(xmlPath); i++ {
+		switch c := xmlPath[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case c == '/' && depth == 0:
+			steps = append(steps, xmlPath[start:i])
+			start = i + 1
+		}
+	}
+	return append(steps, xmlPath[start:])
+}
@@ -0,0 +1,71 @@
+package xmlcomparator
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPathNames(t *testing.T) {
+	assertT := assert.New(t)
+
+	assertT.Equal([]string{}, pathNames(""))
+	assertT.Equal([]string{"a"}, pathNames("/a"))
+	assertT.Equal([]string{"a", "b", "c"}, pathNames("/a/b[1]/c"))
+	assertT.Equal([]string{"a", "b", "@x"}, pathNames("/a/c:b[2]/@c:x"))
+	assertT.Equal([]string{"a", "b", "@x"}, pathNames("/*[local-name()='a']/*[local-name()='b'][2]/@*[local-name()='x']"))
+	assertT.Equal([]string{"a", "item", "c"}, pathNames("/a/item[@id='1/2']/c"))
+}
+
+func TestIgnoreRuleMatching(t *testing.T) {
+	assertT := assert.New(t)
+
+	timestamp1 := &xml.Attr{Name: xml.Name{Local: "timestamp"}, Value: "1"}
+	timestamp2 := &xml.Attr{Name: xml.Name{Local: "timestamp"}, Value: "2"}
+	attrDiff := createAttributeDiff(timestamp1, timestamp2,
+		diffLocation{xmlPath1: "/envelope/header/msg[1]/@timestamp", xmlPath2: "/envelope/header/msg[1]/@timestamp"})
+	textDiff := createTextDiff(DiffContent, "2023-08-27", "2024-01-01", diffLocation{xmlPath1: "/envelope/body/date"})
+
+	tests := []struct {
+		rule    IgnoreRule
+		diff    XmlDiff
+		matches bool
+	}{
+		{IgnoreRule{}, textDiff, true},
+		{IgnoreRule{Type: DiffContent}, textDiff, true},
+		{IgnoreRule{Type: DiffAttributes}, textDiff, false},
+		{IgnoreRule{Path: "/envelope/body/*"}, textDiff, true},
+		{IgnoreRule{Path: "/envelope/header/**"}, textDiff, false},
+		{IgnoreRule{Path: "/envelope/header/**", Attr: "timestamp"}, attrDiff, true},
+		{IgnoreRule{Path: "/envelope/header/**", Attr: "*"}, attrDiff, true},
+		{IgnoreRule{Path: "/envelope/header/**", Attr: "id"}, attrDiff, false},
+		{IgnoreRule{Attr: "timestamp"}, textDiff, false},
+		{IgnoreRule{Path: "//msg/@timestamp"}, attrDiff, true},
+		{IgnoreRule{Path: "//msg/@*"}, attrDiff, true},
+		{IgnoreRule{Path: "//msg/*"}, attrDiff, false},
+		{IgnoreRule{Path: "/envelope/header/msg", Attr: "timestamp"}, attrDiff, true},
+		{IgnoreRule{Path: "/envelope/header/*", Attr: "timestamp"}, attrDiff, true},
+		{IgnoreRule{Path: "/envelope/header", Attr: "timestamp"}, attrDiff, false},
+		{IgnoreRule{Value: `^\d{4}-\d{2}-\d{2}$`}, textDiff, true},
+		{IgnoreRule{Value: `^2023`}, textDiff, false},
+		{IgnoreRule{Value: `^\d$`}, attrDiff, true},
+		{IgnoreRule{Value: `.*`}, createOrderDiff(2, []ChildMove{}, diffLocation{xmlPath1: "/a"}), false},
+	}
+
+	for _, tt := range tests {
+		rule, err := compileIgnoreRule(0, tt.rule)
+		assertT.Nil(err)
+		assertT.Equal(tt.matches, rule.matches(tt.diff), "%+v vs %s", tt.rule, tt.diff.DescribeDiff())
+	}
+}
+
+func TestInvalidIgnoreRules(t *testing.T) {
+	assertT := assert.New(t)
+
+	_, err := compileIgnoreRule(2, IgnoreRule{Path: "/a/@"})
+	assertT.Equal("invalid pattern #2 '/a/@': missing attribute name", err.Error())
+
+	_, err = compileIgnoreRule(3, IgnoreRule{Value: "("})
+	assertT.Equal("invalid pattern #3 '(': error parsing regexp: missing closing ): `(`", err.Error())
+}
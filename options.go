@@ -0,0 +1,568 @@
+package xmlcomparator
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+)
+
+const (
+	defaultNumericTolerance = 1.e-6
+)
+
+// Comparison option
+type Option func(*config) error
+
+// Style of XML paths in discrepancies
+type PathStyle int
+
+const (
+	CompactPathStyle    PathStyle = iota // zero-based index among all siblings, omitted for the only child, like `/a/b[1]`
+	XPathStyle                           // XPath with namespace prefixes and 1-based index among same-named siblings, like `/a/c:b[2]`
+	LocalNameXPathStyle                  // XPath with local names, like `/*[local-name()='a']/*[local-name()='b'][2]`
+)
+
+// Error of an invalid pattern in comparison options
+type PatternError struct {
+	Index   int    // index of the pattern in the option arguments
+	Pattern string // invalid pattern
+	Err     error  // cause of the error
+}
+
+func (err *PatternError) Error() string {
+	return fmt.Sprintf("invalid pattern #%d '%s': %v", err.Index, err.Pattern, err.Err)
+}
+
+func (err *PatternError) Unwrap() error {
+	return err.Err
+}
+
+// Comparison mode of numeric values
+type NumericMode int
+
+const (
+	FloatNumbers         NumericMode = iota // floating point numbers equal within tolerances
+	DecimalNumbers                          // exact decimals regardless of the scale, like `1.50` and `1.5`
+	ScaledDecimalNumbers                    // exact decimals with the same scale - `1.50` differs from `1.5`
+)
+
+// Tolerance for comparison of numeric values.
+// Numbers are equal if they differ within either of tolerances; zero tolerances require exact equality.
+type NumericTolerance struct {
+	Absolute float64 // maximal absolute difference
+	Relative float64 // maximal difference relative to the magnitude of numbers
+}
+
+// Comparison settings
+type config struct {
+	stopOnFirst          bool
+	ignoredDiscrepancies []*regexp.Regexp
+	ignoreRules          []*ignoreRule
+	tolerance            NumericTolerance
+	pathTolerances       []pathTolerance
+	numericMode          NumericMode
+	whitespace           WhitespaceMode
+	compareComments      bool
+	compareProcInsts     bool
+	compareDoctype       bool
+	distinctCData        bool
+	namespaceMap         map[string]string
+	ignoreNamespaces     bool
+	lexicalNamespaces    bool
+	qnameAll             bool
+	qnamePaths           []*pathPattern
+	caseInsensitiveNames bool
+	caseValuesAll        bool
+	caseValuePaths       []*pathPattern
+	unicodeForm          UnicodeForm
+	foldWidth            bool
+	foldCase             bool
+	unorderedAll         bool
+	unorderedPaths       []*pathPattern
+	matchKeys            []matchKey
+	excludedPaths        []*pathPattern
+	pathStyle            PathStyle
+}
+
+// Numeric tolerance for nodes and attributes matching the path pattern
+type pathTolerance struct {
+	pattern   *pathPattern
+	tolerance NumericTolerance
+}
+
+// Key for pairing sibling elements
+type matchKey struct {
+	pattern *pathPattern
+	key     string
+}
+
+// Creates default comparison settings.
+func newConfig() *config {
+	return &config{
+		ignoredDiscrepancies: make([]*regexp.Regexp, 0),
+		ignoreRules:          make([]*ignoreRule, 0),
+		tolerance:            NumericTolerance{Relative: defaultNumericTolerance},
+	}
+}
+
+// Creates comparison settings from the options.
+func createConfig(options []Option) (*config, error) {
+	cfg := newConfig()
+	for _, option := range options {
+		if err := option(cfg); err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
+
+// Stops comparison on the first difference.
+func WithStopOnFirst() Option {
+	return func(cfg *config) error {
+		cfg.stopOnFirst = true
+		return nil
+	}
+}
+
+// Filters out discrepancies with messages matching any of regular expressions.
+//   - patterns - regular expressions for ignored discrepancies
+func WithIgnoredDiscrepancies(patterns ...string) Option {
+	return func(cfg *config) error {
+		regexes, err := CompileIgnoredDiscrepancies(patterns)
+		if err != nil {
+			return err
+		}
+		cfg.ignoredDiscrepancies = append(cfg.ignoredDiscrepancies, regexes...)
+		return nil
+	}
+}
+
+// Filters out discrepancies matching any of rules.
+//   - rules - rules for ignored discrepancies
+func WithIgnoreRules(rules ...IgnoreRule) Option {
+	return func(cfg *config) error {
+		errs := make([]error, 0)
+		for i := range rules {
+			rule, err := compileIgnoreRule(i, rules[i])
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			cfg.ignoreRules = append(cfg.ignoreRules, rule)
+		}
+		return errors.Join(errs...)
+	}
+}
+
+// Validates and compiles regular expressions for ignored discrepancies.
+//   - patterns - regular expressions
+//
+// Returns: compiled expressions or error with `PatternError` for every invalid pattern
+func CompileIgnoredDiscrepancies(patterns []string) ([]*regexp.Regexp, error) {
+	regexes := make([]*regexp.Regexp, len(patterns))
+	errs := make([]error, 0)
+	for i := range patterns {
+		regex, err := regexp.Compile(patterns[i])
+		if err != nil {
+			errs = append(errs, &PatternError{Index: i, Pattern: patterns[i], Err: err})
+		}
+		regexes[i] = regex
+	}
+
+	if len(errs) != 0 {
+		return nil, errors.Join(errs...)
+	}
+	return regexes, nil
+}
+
+// Sets relative tolerance for comparison of numeric texts and attribute values - by default 1e-6.
+//   - eps - non-negative relative tolerance
+func WithNumericTolerance(eps float64) Option {
+	return func(cfg *config) error {
+		if err := validateTolerance(eps); err != nil {
+			return err
+		}
+		cfg.tolerance.Relative = eps
+		return nil
+	}
+}
+
+// Sets absolute tolerance for comparison of numeric texts and attribute values - by default 0.
+//   - delta - non-negative absolute tolerance
+func WithAbsoluteTolerance(delta float64) Option {
+	return func(cfg *config) error {
+		if err := validateTolerance(delta); err != nil {
+			return err
+		}
+		cfg.tolerance.Absolute = delta
+		return nil
+	}
+}
+
+// Overrides numeric tolerances for nodes matching the path pattern.
+// The first matching pattern takes effect.
+//   - path - path pattern of elements, like "//price", or attributes, like "//reading/@value"
+//   - tolerance - tolerances for the matching nodes; zero value requires exact equality
+func WithPathTolerance(path string, tolerance NumericTolerance) Option {
+	return func(cfg *config) error {
+		pattern, err := compilePathPattern(path)
+		if err != nil {
+			return &PatternError{Index: 0, Pattern: path, Err: err}
+		}
+		if err := errors.Join(validateTolerance(tolerance.Absolute), validateTolerance(tolerance.Relative)); err != nil {
+			return err
+		}
+		cfg.pathTolerances = append(cfg.pathTolerances, pathTolerance{pattern: pattern, tolerance: tolerance})
+		return nil
+	}
+}
+
+// Sets comparison mode of numeric texts and attribute values - by default `FloatNumbers`.
+// Tolerances don't apply to decimal modes.
+//   - mode - numeric mode
+func WithNumericMode(mode NumericMode) Option {
+	return func(cfg *config) error {
+		if mode < FloatNumbers || mode > ScaledDecimalNumbers {
+			return fmt.Errorf("invalid numeric mode %d", mode)
+		}
+		cfg.numericMode = mode
+		return nil
+	}
+}
+
+// Sets handling of whitespace in text content - by default `TrimWhitespace`.
+// Texts of elements with `xml:space="preserve"` are always compared as is.
+//   - mode - whitespace mode
+func WithWhitespaceMode(mode WhitespaceMode) Option {
+	return func(cfg *config) error {
+		if mode < TrimWhitespace || mode > IgnoreWhitespace {
+			return fmt.Errorf("invalid whitespace mode %d", mode)
+		}
+		cfg.whitespace = mode
+		return nil
+	}
+}
+
+// Normalizes Unicode of texts and attribute values before comparison, so that "José" in composed and decomposed forms is the same.
+//   - form - normalization form
+//   - foldings - optional width and case foldings applied after normalization
+func WithUnicodeNormalization(form UnicodeForm, foldings ...UnicodeFolding) Option {
+	return func(cfg *config) error {
+		if form < NoNormalization || form > NFKC {
+			return fmt.Errorf("invalid unicode form %d", form)
+		}
+		cfg.unicodeForm = form
+
+		for _, folding := range foldings {
+			switch folding {
+			case FoldWidth:
+				cfg.foldWidth = true
+			case FoldCase:
+				cfg.foldCase = true
+			default:
+				return fmt.Errorf("invalid unicode folding %d", folding)
+			}
+		}
+		return nil
+	}
+}
+
+// Compares comments of elements, including ones before the root element.
+func WithComments() Option {
+	return func(cfg *config) error {
+		cfg.compareComments = true
+		return nil
+	}
+}
+
+// Compares processing instructions, like `<?xml-stylesheet href="a.xsl"?>`, except the XML declaration.
+func WithProcessingInstructions() Option {
+	return func(cfg *config) error {
+		cfg.compareProcInsts = true
+		return nil
+	}
+}
+
+// Compares document type declarations.
+func WithDoctype() Option {
+	return func(cfg *config) error {
+		cfg.compareDoctype = true
+		return nil
+	}
+}
+
+// Treats CDATA sections as distinct from the same escaped text.
+func WithDistinctCData() Option {
+	return func(cfg *config) error {
+		cfg.distinctCData = true
+		return nil
+	}
+}
+
+// Compares namespace URI as if it were another URI, like "urn:api:v1" -> "urn:api:v2".
+// Mapping is applied to names of elements and attributes in both samples when they are compared; messages show source namespaces.
+//   - from - mapped namespace URI
+//   - to - namespace URI to compare with
+func WithNamespaceMapping(from string, to string) Option {
+	return func(cfg *config) error {
+		if from == "" || to == "" {
+			return errors.New("empty namespace in mapping '" + from + "' -> '" + to + "'")
+		}
+		if cfg.namespaceMap == nil {
+			cfg.namespaceMap = make(map[string]string)
+		}
+		cfg.namespaceMap[from] = to
+		return nil
+	}
+}
+
+// Ignores namespaces of elements and attributes - only local names are compared.
+func WithIgnoredNamespaces() Option {
+	return func(cfg *config) error {
+		cfg.ignoreNamespaces = true
+		return nil
+	}
+}
+
+// Reports differences of namespace declarations and prefixes of elements,
+// which are ignored by default as long as elements have the same namespaces.
+func WithLexicalNamespaces() Option {
+	return func(cfg *config) error {
+		cfg.lexicalNamespaces = true
+		return nil
+	}
+}
+
+// Compares values that are qualified names, like `xsi:type="c:Customer"`, by namespace URIs and local names.
+// Prefixes are resolved against namespace declarations in scope of the element; values without declared prefixes are compared as is.
+//   - paths - path patterns of elements, like "//faultcode", or attributes, like "//@type"; all values when none is given
+func WithQNameValues(paths ...string) Option {
+	return func(cfg *config) error {
+		if len(paths) == 0 {
+			cfg.qnameAll = true
+			return nil
+		}
+
+		patterns, err := compilePathPatterns(paths)
+		if err != nil {
+			return err
+		}
+		cfg.qnamePaths = append(cfg.qnamePaths, patterns...)
+		return nil
+	}
+}
+
+// Compares names of elements and attributes ignoring case, like `CustomerID` and `customerId`.
+// Path patterns of other options are still matched with case.
+func WithCaseInsensitiveNames() Option {
+	return func(cfg *config) error {
+		cfg.caseInsensitiveNames = true
+		return nil
+	}
+}
+
+// Compares texts and attribute values ignoring case.
+//   - paths - path patterns of elements, like "//status", or attributes, like "//@currency"; all values when none is given.
+//     Patterns are matched with case, so names in different case need separate patterns
+func WithCaseInsensitiveValues(paths ...string) Option {
+	return func(cfg *config) error {
+		if len(paths) == 0 {
+			cfg.caseValuesAll = true
+			return nil
+		}
+
+		patterns, err := compilePathPatterns(paths)
+		if err != nil {
+			return err
+		}
+		cfg.caseValuePaths = append(cfg.caseValuePaths, patterns...)
+		return nil
+	}
+}
+
+func validateTolerance(eps float64) error {
+	if eps < 0 || math.IsNaN(eps) {
+		return fmt.Errorf("invalid numeric tolerance %g", eps)
+	}
+	return nil
+}
+
+// Treats children of the nodes as an unordered collection.
+// Children are paired by the best match and only added, removed or changed ones are reported.
+//   - paths - path patterns of the nodes with unordered children; all nodes when none is given
+func WithUnorderedChildren(paths ...string) Option {
+	return func(cfg *config) error {
+		if len(paths) == 0 {
+			cfg.unorderedAll = true
+			return nil
+		}
+
+		patterns, err := compilePathPatterns(paths)
+		if err != nil {
+			return err
+		}
+		cfg.unorderedPaths = append(cfg.unorderedPaths, patterns...)
+		return nil
+	}
+}
+
+// Pairs sibling elements by the key value before comparing them.
+// Elements with keys that have no counterpart are reported as added or removed.
+//   - path - path pattern of the keyed elements, like "/order/item"
+//   - key - attribute name prefixed with `@`, like "@id", or name of the child element, like "isbn"
+func WithMatchKey(path string, key string) Option {
+	return func(cfg *config) error {
+		if key == "" || key == attrPrefix {
+			return errors.New("missing match key for path pattern '" + path + "'")
+		}
+
+		pattern, err := compilePathPattern(path)
+		if err != nil {
+			return &PatternError{Index: 0, Pattern: path, Err: err}
+		}
+		cfg.matchKeys = append(cfg.matchKeys, matchKey{pattern: pattern, key: key})
+		return nil
+	}
+}
+
+// Excludes elements and attributes from comparison - as if they were absent in both samples.
+//   - paths - path patterns of excluded elements, like "/envelope/header", or attributes, like "//@timestamp"
+func WithExcludedPaths(paths ...string) Option {
+	return func(cfg *config) error {
+		patterns, err := compilePathPatterns(paths)
+		if err != nil {
+			return err
+		}
+		cfg.excludedPaths = append(cfg.excludedPaths, patterns...)
+		return nil
+	}
+}
+
+// Sets style of XML paths in discrepancies - by default `CompactPathStyle`.
+//   - style - path style
+func WithPathStyle(style PathStyle) Option {
+	return func(cfg *config) error {
+		if style < CompactPathStyle || style > LocalNameXPathStyle {
+			return fmt.Errorf("invalid path style %d", style)
+		}
+		cfg.pathStyle = style
+		return nil
+	}
+}
+
+func compilePathPatterns(paths []string) ([]*pathPattern, error) {
+	patterns := make([]*pathPattern, len(paths))
+	errs := make([]error, 0)
+	for i := range paths {
+		pattern, err := compilePathPattern(paths[i])
+		if err != nil {
+			errs = append(errs, &PatternError{Index: i, Pattern: paths[i], Err: err})
+		}
+		patterns[i] = pattern
+	}
+
+	if len(errs) != 0 {
+		return nil, errors.Join(errs...)
+	}
+	return patterns, nil
+}
+
+func (cfg *config) isUnordered(node *parseNode) bool {
+	return cfg.unorderedAll || anyMatchesNode(cfg.unorderedPaths, node)
+}
+
+// Provides namespace URI to compare after mapping.
+func (cfg *config) mapNamespace(space string) string {
+	switch {
+	case space == xmlNamespace:
+		return space
+	case cfg.ignoreNamespaces:
+		return ""
+	}
+	if mapped, ok := cfg.namespaceMap[space]; ok {
+		return mapped
+	}
+	return space
+}
+
+// Provides namespace declaration to compare, like "xmlns:c=urn:c", with the mapped namespace URI.
+func (cfg *config) mapDecl(decl string) string {
+	prefix, space, _ := strings.Cut(decl, "=")
+	return prefix + "=" + cfg.mapNamespace(space)
+}
+
+// Provides the value for comparison - Unicode normalized value, then expanded name with mapped namespace,
+// like "{urn:c}Customer", if the value is a qualified name, and lower case value if the case is ignored.
+//   - node - element of the value
+//   - names - names of the nodes on the path from the root, ending with the attribute name prefixed with `@`, if any
+func (cfg *config) comparedValue(node *parseNode, names []string, value string) string {
+	value = cfg.normalizeUnicode(value)
+	if cfg.qnameAll || anyMatches(cfg.qnamePaths, names) {
+		if space, local, ok := node.resolveQName(value); ok {
+			value = "{" + cfg.mapNamespace(space) + "}" + local
+		}
+	}
+	if cfg.caseValuesAll || anyMatches(cfg.caseValuePaths, names) {
+		value = strings.ToLower(value)
+	}
+	return value
+}
+
+// Checks if compared values depend on paths of nodes.
+func (cfg *config) hasValuePaths() bool {
+	return len(cfg.qnamePaths) != 0 || len(cfg.caseValuePaths) != 0
+}
+
+// Provides the name of the element or attribute for comparison.
+func (cfg *config) nameKey(name string) string {
+	if cfg.caseInsensitiveNames {
+		return strings.ToLower(name)
+	}
+	return name
+}
+
+// Provides numeric tolerance for the node or attribute.
+//   - names - names of the nodes on the path from the root, ending with the attribute name prefixed with `@`, if any
+func (cfg *config) toleranceFor(names []string) NumericTolerance {
+	for i := range cfg.pathTolerances {
+		if cfg.pathTolerances[i].pattern.matches(names) {
+			return cfg.pathTolerances[i].tolerance
+		}
+	}
+	return cfg.tolerance
+}
+
+// Provides the name of the element used for pairing siblings - the element name with the key, if any.
+func (cfg *config) matchingName(node *parseNode) string {
+	return cfg.keyedName(node, nodeName(node))
+}
+
+// Appends the key of the element to the name, like "item[@id='1']".
+func (cfg *config) keyedName(node *parseNode, name string) string {
+	for i := range cfg.matchKeys {
+		if !cfg.matchKeys[i].pattern.matchesNode(node) {
+			continue
+		}
+
+		key := cfg.matchKeys[i].key
+		if value, ok := node.keyValue(key, cfg.nameKey); ok {
+			return name + "[" + key + "='" + value + "']"
+		}
+		break
+	}
+	return name
+}
+
+// Checks if attributes have the same namespace after mapping and local name.
+func (cfg *config) sameAttrName(attr1 *xml.Attr, attr2 *xml.Attr) bool {
+	return cfg.mapNamespace(attrSpace(attr1)) == cfg.mapNamespace(attrSpace(attr2)) && cfg.nameKey(attrName(attr1)) == cfg.nameKey(attrName(attr2))
+}
+
+// Provides the name of the element for pairing siblings, which is compared for equality.
+// Only the element name is case-insensitive; key values are compared as is.
+func (cfg *config) pairingName(node *parseNode) string {
+	return cfg.keyedName(node, cfg.nameKey(nodeName(node)))
+}
@@ -0,0 +1,172 @@
+package xmlcomparator
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigCreation(t *testing.T) {
+	assertT := assert.New(t)
+
+	cfg, err := createConfig([]Option{})
+	assertT.Nil(err)
+	assertT.False(cfg.stopOnFirst)
+	assertT.Equal(0, len(cfg.ignoredDiscrepancies))
+	assertT.Equal(NumericTolerance{Relative: defaultNumericTolerance}, cfg.tolerance)
+	assertT.False(cfg.unorderedAll)
+
+	cfg, err = createConfig([]Option{WithStopOnFirst(), WithIgnoredDiscrepancies("^a", "b$"), WithNumericTolerance(0.1)})
+	assertT.Nil(err)
+	assertT.True(cfg.stopOnFirst)
+	assertT.Equal(2, len(cfg.ignoredDiscrepancies))
+	assertT.Equal(0.1, cfg.tolerance.Relative)
+
+	cfg, err = createConfig([]Option{WithUnorderedChildren()})
+	assertT.Nil(err)
+	assertT.True(cfg.unorderedAll)
+
+	cfg, err = createConfig([]Option{WithUnorderedChildren("/a/b", "c")})
+	assertT.Nil(err)
+	assertT.False(cfg.unorderedAll)
+	assertT.Equal(2, len(cfg.unorderedPaths))
+
+	cfg, err = createConfig([]Option{WithUnorderedChildren("/a/@b/c")})
+	assertT.Nil(cfg)
+	assertT.NotNil(err)
+
+	cfg, err = createConfig([]Option{WithPathStyle(XPathStyle)})
+	assertT.Nil(err)
+	assertT.Equal(XPathStyle, cfg.pathStyle)
+
+	cfg, err = createConfig([]Option{WithPathStyle(PathStyle(7))})
+	assertT.Nil(cfg)
+	assertT.Equal("invalid path style 7", err.Error())
+}
+
+func TestInvalidConfigOptions(t *testing.T) {
+	assertT := assert.New(t)
+
+	_, err := createConfig([]Option{WithIgnoredDiscrepancies("a", "(")})
+	var patternErr *PatternError
+	assertT.True(errors.As(err, &patternErr))
+	assertT.Equal(1, patternErr.Index)
+	assertT.Equal("(", patternErr.Pattern)
+	assertT.NotNil(errors.Unwrap(patternErr))
+
+	_, err = createConfig([]Option{WithUnorderedChildren("/a", "/b/@")})
+	assertT.Equal("invalid pattern #1 '/b/@': missing attribute name", err.Error())
+
+	_, err = createConfig([]Option{WithNumericTolerance(-1)})
+	assertT.Equal("invalid numeric tolerance -1", err.Error())
+
+	_, err = createConfig([]Option{WithNumericMode(NumericMode(5))})
+	assertT.Equal("invalid numeric mode 5", err.Error())
+
+	_, err = createConfig([]Option{WithWhitespaceMode(WhitespaceMode(9))})
+	assertT.Equal("invalid whitespace mode 9", err.Error())
+
+	_, err = createConfig([]Option{WithUnicodeNormalization(UnicodeForm(3))})
+	assertT.Equal("invalid unicode form 3", err.Error())
+
+	_, err = createConfig([]Option{WithUnicodeNormalization(NFC, UnicodeFolding(4))})
+	assertT.Equal("invalid unicode folding 4", err.Error())
+
+	_, err = createConfig([]Option{WithNamespaceMapping("urn:a", "")})
+	assertT.Equal("empty namespace in mapping 'urn:a' -> ''", err.Error())
+
+	_, err = createConfig([]Option{WithAbsoluteTolerance(-2)})
+	assertT.Equal("invalid numeric tolerance -2", err.Error())
+
+	_, err = createConfig([]Option{WithPathTolerance("//a", NumericTolerance{Relative: -3})})
+	assertT.Equal("invalid numeric tolerance -3", err.Error())
+
+	_, err = createConfig([]Option{WithPathTolerance("/a/@", NumericTolerance{})})
+	assertT.Equal("invalid pattern #0 '/a/@': missing attribute name", err.Error())
+}
+
+func TestMapNamespace(t *testing.T) {
+	assertT := assert.New(t)
+
+	cfg, _ := createConfig([]Option{WithNamespaceMapping("urn:a", "urn:b")})
+	assertT.Equal("urn:b", cfg.mapNamespace("urn:a"))
+	assertT.Equal("urn:c", cfg.mapNamespace("urn:c"))
+
+	cfg, _ = createConfig([]Option{WithIgnoredNamespaces()})
+	assertT.Equal("", cfg.mapNamespace("urn:a"))
+	assertT.Equal(xmlNamespace, cfg.mapNamespace(xmlNamespace))
+}
+
+func TestCaseInsensitivity(t *testing.T) {
+	assertT := assert.New(t)
+
+	cfg, _ := createConfig([]Option{})
+	assertT.Equal("CustomerID", cfg.nameKey("CustomerID"))
+
+	cfg, err := createConfig([]Option{WithCaseInsensitiveNames(), WithCaseInsensitiveValues("//status", "//@currency")})
+	assertT.Nil(err)
+	assertT.Equal("customerid", cfg.nameKey("CustomerID"))
+
+	root, _ := parseXML(`<a/>`)
+	assertT.Equal("paid", cfg.comparedValue(root, []string{"a", "status"}, "PAID"))
+	assertT.Equal("eur", cfg.comparedValue(root, []string{"a", "@currency"}, "EUR"))
+	assertT.Equal("Note", cfg.comparedValue(root, []string{"a", "note"}, "Note"))
+
+	_, err = createConfig([]Option{WithCaseInsensitiveValues("/a/@")})
+	assertT.NotNil(err)
+}
+
+func TestToleranceFor(t *testing.T) {
+	assertT := assert.New(t)
+
+	cfg, err := createConfig([]Option{WithPathTolerance("//price", NumericTolerance{}),
+		WithPathTolerance("//@value", NumericTolerance{Absolute: 1}), WithAbsoluteTolerance(0.5)})
+	assertT.Nil(err)
+
+	assertT.Equal(NumericTolerance{}, cfg.toleranceFor([]string{"a", "price"}))
+	assertT.Equal(NumericTolerance{Absolute: 1}, cfg.toleranceFor([]string{"a", "@value"}))
+	assertT.Equal(NumericTolerance{Absolute: 0.5, Relative: defaultNumericTolerance}, cfg.toleranceFor([]string{"a", "b"}))
+}
+
+func TestMatchingName(t *testing.T) {
+	assertT := assert.New(t)
+
+	cfg, err := createConfig([]Option{WithMatchKey("/a/b", "@id"), WithMatchKey("c", "d")})
+	assertT.Nil(err)
+
+	root, _ := parseXML(`<a><b id="1"/><b/><c><d> 2 </d></c><e id="3"/></a>`)
+	assertT.Equal("a", cfg.matchingName(root))
+	assertT.Equal("b[@id='1']", cfg.matchingName(&root.Children[0]))
+	assertT.Equal("b", cfg.matchingName(&root.Children[1]))
+	assertT.Equal("c[d='2']", cfg.matchingName(&root.Children[2]))
+	assertT.Equal("e", cfg.matchingName(&root.Children[3]))
+
+	cfg, _ = createConfig([]Option{WithCaseInsensitiveNames(), WithMatchKey("//I", "@id")})
+	root, _ = parseXML(`<a><I id="A"/></a>`)
+	assertT.Equal("I[@id='A']", cfg.matchingName(&root.Children[0]))
+	assertT.Equal("i[@id='A']", cfg.pairingName(&root.Children[0]))
+
+	// Key names are case-insensitive as well
+	root, _ = parseXML(`<a><I ID="A"/><I><Id>B</Id></I></a>`)
+	assertT.Equal("i[@id='A']", cfg.pairingName(&root.Children[0]))
+	cfg, _ = createConfig([]Option{WithCaseInsensitiveNames(), WithMatchKey("//I", "id")})
+	assertT.Equal("i[id='B']", cfg.pairingName(&root.Children[1]))
+
+	_, err = createConfig([]Option{WithMatchKey("/a/b", "@")})
+	assertT.NotNil(err)
+	_, err = createConfig([]Option{WithMatchKey("", "@id")})
+	assertT.NotNil(err)
+}
+
+func TestCompileIgnoredDiscrepancies(t *testing.T) {
+	assertT := assert.New(t)
+
+	regexes, err := CompileIgnoredDiscrepancies([]string{"^a", "b$"})
+	assertT.Nil(err)
+	assertT.Equal(2, len(regexes))
+
+	regexes, err = CompileIgnoredDiscrepancies([]string{"^a", "*"})
+	assertT.Nil(regexes)
+	assertT.Equal("invalid pattern #1 '*': error parsing regexp: missing argument to repetition operator: `*`", err.Error())
+}
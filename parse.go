@@ -1,7 +1,6 @@
 package xmlcomparator
 
 import (
-	"bytes"
 	"encoding/xml"
 	"hash/crc32"
 	"strings"
@@ -9,37 +8,57 @@ import (
 
 var crc32c = crc32.MakeTable(crc32.Castagnoli)
 
+const (
+	cdataStart    = "<![CDATA["
+	cdataEnd      = "]]>"
+	doctypePrefix = "DOCTYPE"
+)
+
 type parseNode struct {
-	XMLName  xml.Name
-	Attrs    []xml.Attr  `xml:"-"`
-	Content  []byte      `xml:",innerxml"`
-	CharData string      `xml:",chardata"`
-	Children []parseNode `xml:",any"`
-	Parent   *parseNode  `xml:"-"`
-	Hash     uint32      `xml:"-"`
+	XMLName      xml.Name
+	Prefix       string // namespace prefix of the element in the source
+	Attrs        []xml.Attr
+	Content      []byte     // inner XML
+	CharData     string     // concatenation of all text segments
+	Texts        []string   // text segments before every child and after the last one
+	Comments     []string   // comments of the element
+	ProcInsts    []string   // processing instructions, like "xml-stylesheet href='a.xsl'"
+	Doctype      string     // document type declaration; only in the prolog
+	Prolog       *parseNode // markup before the root element; only in the root
+	Epilog       *parseNode // markup after the root element; only in the root
+	Children     []parseNode
+	ChildNames   []xml.Name // names of children in the source, including excluded ones
+	Parent       *parseNode
+	Index        int // index in the list of siblings in the source
+	Hash         uint32
+	Pos          Position   // position of the start tag
+	AttrPos      []Position // positions of attributes
+	AttrPrefixes []string   // namespace prefixes of attributes in the source
 }
 
-// Unmarshals XML data into a Node structure - `Decoder` requirement to parse attributes.
-func (n *parseNode) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
-	n.Attrs = start.Attr
-	type node parseNode
-
-	return d.DecodeElement((*node)(n), &start)
+// Unmarshals XML string into a Node structure using default comparison settings
+//   - xmlString - XML string to unmarshal
+//
+// Returns: root node of the XML tree and error if any
+func parseXML(xmlString string) (*parseNode, error) {
+	return parseXMLEx(xmlString, newConfig())
 }
 
 // Unmarshals XML string into a Node structure
 //   - xmlString - XML string to unmarshal
+//   - cfg - comparison settings that affect node hashes
 //
 // Returns: root node of the XML tree and error if any
-func parseXML(xmlString string) (*parseNode, error) {
-	buf := bytes.NewBuffer([]byte(xmlString))
-	dec := xml.NewDecoder(buf)
-
-	var root parseNode
-	if err := dec.Decode(&root); err != nil {
+func parseXMLEx(xmlString string, cfg *config) (*parseNode, error) {
+	root, err := decodeTree(xmlString, cfg)
+	if err != nil {
 		return nil, err
 	}
 
+	if len(cfg.excludedPaths) != 0 {
+		root.exclude([]string{nodeName(root)}, cfg.excludedPaths)
+	}
+
 	root.walk(func(n *parseNode) bool {
 		for i := range n.Children {
 			n.Children[i].Parent = n
@@ -47,9 +66,157 @@ func parseXML(xmlString string) (*parseNode, error) {
 		return true
 	})
 
-	root.hashCode()
+	root.hashCode(cfg)
+
+	return root, nil
+}
+
+// Element being decoded
+type openElement struct {
+	node         *parseNode
+	contentStart int
+}
+
+// State of decoding XML string into a tree
+type treeDecoder struct {
+	source string
+	dec    *xml.Decoder
+	lines  *lineIndex
+	cfg    *config
+	stack  []openElement
+	prolog *parseNode
+	root   *parseNode
+}
+
+// Decodes the first element of XML string with all its descendants recording their positions.
+// Comments, processing instructions and document type before and after the element are kept in its prolog and epilog.
+//   - cfg - comparison settings; CDATA sections are kept as markup if they are distinct from text
+func decodeTree(xmlString string, cfg *config) (*parseNode, error) {
+	td := &treeDecoder{source: xmlString, dec: xml.NewDecoder(strings.NewReader(xmlString)), lines: createLineIndex(xmlString), cfg: cfg,
+		stack: make([]openElement, 0), prolog: &parseNode{}}
+
+	for {
+		offset := int(td.dec.InputOffset())
+		token, err := td.dec.Token()
+		if err == nil {
+			err = td.decodeToken(token, offset)
+		}
+		if err != nil {
+			// Content after the root element isn't validated
+			if td.root != nil {
+				return td.root, nil
+			}
+			return nil, err
+		}
+	}
+}
+
+// Adds the token to the tree.
+//   - offset - offset of the token in the source
+func (td *treeDecoder) decodeToken(token xml.Token, offset int) error {
+	switch t := token.(type) {
+	case xml.StartElement:
+		return td.startElement(t, offset)
+	case xml.EndElement:
+		td.endElement(offset)
+	case xml.CharData:
+		td.charData(t, offset)
+	case xml.Comment:
+		node := td.markupNode()
+		node.Comments = append(node.Comments, string(t))
+	case xml.ProcInst:
+		// XML declaration isn't compared
+		if t.Target != "xml" {
+			node := td.markupNode()
+			node.ProcInsts = append(node.ProcInsts, strings.TrimSpace(t.Target+" "+string(t.Inst)))
+		}
+	case xml.Directive:
+		if len(td.stack) == 0 && strings.HasPrefix(string(t), doctypePrefix) {
+			td.prolog.Doctype = strings.TrimSpace(string(t)[len(doctypePrefix):])
+		}
+	}
+	return nil
+}
+
+// Opens the element; elements after the root one are skipped.
+func (td *treeDecoder) startElement(t xml.StartElement, offset int) error {
+	if td.root != nil {
+		// Only the first element is compared
+		return td.dec.Skip()
+	}
 
-	return &root, nil
+	endOffset := int(td.dec.InputOffset())
+	node := &parseNode{XMLName: t.Name, Attrs: xml.CopyToken(t).(xml.StartElement).Attr, Pos: td.lines.position(offset), Texts: []string{""}}
+	node.Prefix = sourcePrefix(td.source[offset+1 : endOffset])
+	node.AttrPos = make([]Position, len(node.Attrs))
+	node.AttrPrefixes = make([]string, len(node.Attrs))
+	for i, attrOffset := range findAttrOffsets(td.source[offset:endOffset], offset, len(node.Attrs)) {
+		node.AttrPos[i] = td.lines.position(attrOffset)
+		node.AttrPrefixes[i] = sourcePrefix(td.source[attrOffset:endOffset])
+	}
+	td.stack = append(td.stack, openElement{node: node, contentStart: endOffset})
+	return nil
+}
+
+// Closes the element adding it to the parent or making it the root.
+func (td *treeDecoder) endElement(offset int) {
+	elem := td.stack[len(td.stack)-1]
+	td.stack = td.stack[:len(td.stack)-1]
+	elem.node.Content = []byte(td.source[elem.contentStart:offset])
+	if len(td.stack) == 0 {
+		td.root = elem.node
+		td.root.Prolog, td.root.Epilog = td.prolog, &parseNode{}
+		return
+	}
+
+	parent := td.stack[len(td.stack)-1].node
+	elem.node.Index = len(parent.Children)
+	parent.Children = append(parent.Children, *elem.node)
+	parent.ChildNames = append(parent.ChildNames, elem.node.XMLName)
+	parent.Texts = append(parent.Texts, "")
+}
+
+// Appends text to the current text segment of the open element.
+func (td *treeDecoder) charData(t xml.CharData, offset int) {
+	if len(td.stack) == 0 {
+		return
+	}
+
+	text := string(t)
+	if td.cfg.distinctCData && strings.HasPrefix(td.source[offset:], cdataStart) {
+		text = cdataStart + text + cdataEnd
+	}
+	node := td.stack[len(td.stack)-1].node
+	node.CharData += text
+	node.Texts[len(node.Texts)-1] += text
+}
+
+// Provides the node that holds comments and processing instructions at the current position.
+func (td *treeDecoder) markupNode() *parseNode {
+	if len(td.stack) > 0 {
+		return td.stack[len(td.stack)-1].node
+	}
+	return td.root.markupHolder(td.prolog)
+}
+
+// Provides the node for markup outside of the root element - the prolog before the root is decoded and the epilog after it.
+func (root *parseNode) markupHolder(prolog *parseNode) *parseNode {
+	if root == nil {
+		return prolog
+	}
+	return root.Epilog
+}
+
+// Extracts namespace prefix of the name that starts the source text, like "c" for `c:item id="1">`.
+func sourcePrefix(source string) string {
+	name := source
+	if end := strings.IndexAny(source, " \t\r\n/>="); end >= 0 {
+		name = source[:end]
+	}
+	if idx := strings.IndexByte(name, ':'); idx >= 0 {
+		return name[:idx]
+	}
+	return ""
 }
 
 // Walks depth-first through the XML tree calling the function for iteslef and then for each child node
@@ -64,29 +231,142 @@ func (node *parseNode) walk(f func(*parseNode) bool) {
 	}
 }
 
+// Recursively removes excluded attributes and children.
+// Should be called before linking children to parents.
+//   - names - names of the nodes on the path from the root to this node
+//   - patterns - path patterns of excluded elements and attributes
+func (node *parseNode) exclude(names []string, patterns []*pathPattern) {
+	names = names[:len(names):len(names)]
+
+	attrs := make([]xml.Attr, 0, len(node.Attrs))
+	attrPos := make([]Position, 0, len(node.AttrPos))
+	attrPrefixes := make([]string, 0, len(node.AttrPrefixes))
+	for i := range node.Attrs {
+		if !anyMatches(patterns, append(names, attrPrefix+attrName(&node.Attrs[i]))) {
+			attrs = append(attrs, node.Attrs[i])
+			attrPos = append(attrPos, node.AttrPos[i])
+			attrPrefixes = append(attrPrefixes, node.AttrPrefixes[i])
+		}
+	}
+	node.Attrs = attrs
+	node.AttrPos = attrPos
+	node.AttrPrefixes = attrPrefixes
+
+	// Text segments around excluded children are merged
+	segments := node.textSegments()
+	texts := []string{segments[0]}
+	children := make([]parseNode, 0, len(node.Children))
+	for i := range node.Children {
+		childNames := append(names, nodeName(&node.Children[i]))
+		if !anyMatches(patterns, childNames) {
+			node.Children[i].exclude(childNames, patterns)
+			children = append(children, node.Children[i])
+			texts = append(texts, segments[i+1])
+		} else {
+			texts[len(texts)-1] += segments[i+1]
+		}
+	}
+	node.Children = children
+	node.Texts = texts
+}
+
+// Provides text segments of the node - before every child and after the last one.
+func (node *parseNode) textSegments() []string {
+	if len(node.Texts) != len(node.Children)+1 {
+		// Not a parsed node - all text at once
+		return append([]string{node.CharData}, make([]string, len(node.Children))...)
+	}
+	return node.Texts
+}
+
 //------- hash code generation -------
 
 // Recursive function
-func (node *parseNode) hashCode() uint32 {
+//   - cfg - comparison settings; hash of unordered children doesn't depend on their order
+func (node *parseNode) hashCode(cfg *config) uint32 {
 	if node.Hash != 0 {
 		return node.Hash
 	}
 
-	node.Hash = crc32.Checksum([]byte(nodeName(node)), crc32c)
-	node.Hash = crc32.Update(node.Hash, crc32c, []byte(strings.TrimSpace(node.CharData)))
+	node.Hash = crc32.Checksum([]byte(cfg.nameKey(nodeName(node))), crc32c)
+	// Names are needed only for values that are compared depending on paths
+	names := make([]string, 0)
+	if cfg.hasValuePaths() {
+		names = node.names()
+	}
+	node.hashText(names, cfg)
+	if cfg.lexicalNamespaces {
+		node.hashLexicalNamespaces(cfg)
+	}
+	node.hashMarkup(cfg)
+	node.hashAttributes(names, cfg)
 
-	for i := range node.Attrs {
-		attrPtr := &node.Attrs[i]
-		if !isNameSpaceAttr(attrPtr) {
-			node.Hash = crc32.Update(node.Hash, crc32c, []byte(attrName(attrPtr)))
-			node.Hash = crc32.Update(node.Hash, crc32c, []byte(attrValue(attrPtr)))
-		}
+	childHashes := make([]uint32, len(node.Children))
+	for i := range node.Children {
+		childHashes[i] = node.Children[i].hashCode(cfg)
+	}
+	if cfg.isUnordered(node) {
+		childHashes = sorted(childHashes, hashComparator)
 	}
 
 	// Cheap and cheerful
-	for i := range node.Children {
-		node.Hash = 31*node.Hash + node.Children[i].hashCode()
+	for _, childHash := range childHashes {
+		node.Hash = 31*node.Hash + childHash
 	}
 
 	return node.Hash
 }
+
+// Adds texts to the hash - the whole text or, in ordered mixed content, every text segment.
+func (node *parseNode) hashText(names []string, cfg *config) {
+	if cfg.isUnordered(node) || len(node.Children) == 0 {
+		node.Hash = crc32.Update(node.Hash, crc32c, []byte(cfg.hashedValue(cfg.comparedValue(node, names, cfg.nodeText(node)))))
+		return
+	}
+
+	// Order of text segments matters
+	for _, text := range cfg.textSegments(node) {
+		node.Hash = crc32.Update(node.Hash, crc32c, []byte(cfg.hashedValue(cfg.comparedValue(node, names, text))))
+		node.Hash = crc32.Update(node.Hash, crc32c, []byte{0})
+	}
+}
+
+// Adds the element prefix, namespace declarations and prefixes of attributes to the hash.
+func (node *parseNode) hashLexicalNamespaces(cfg *config) {
+	node.Hash = crc32.Update(node.Hash, crc32c, []byte(node.Prefix))
+	for _, decl := range node.namespaceDecls() {
+		node.Hash = crc32.Update(node.Hash, crc32c, []byte(cfg.mapDecl(decl)))
+	}
+	for i := range node.Attrs {
+		if !isNameSpaceAttr(&node.Attrs[i]) {
+			node.Hash = crc32.Update(node.Hash, crc32c, []byte(node.attrSourcePrefix(&node.Attrs[i])))
+		}
+	}
+}
+
+// Adds compared comments and processing instructions to the hash.
+func (node *parseNode) hashMarkup(cfg *config) {
+	if cfg.compareComments {
+		for _, comment := range node.Comments {
+			node.Hash = crc32.Update(node.Hash, crc32c, []byte(normalizeWhitespace(comment, cfg.whitespace)))
+		}
+	}
+	if cfg.compareProcInsts {
+		for _, procInst := range node.ProcInsts {
+			node.Hash = crc32.Update(node.Hash, crc32c, []byte(procInst))
+		}
+	}
+}
+
+// Adds namespaces, names and values of attributes to the hash.
+func (node *parseNode) hashAttributes(names []string, cfg *config) {
+	for i := range node.Attrs {
+		attrPtr := &node.Attrs[i]
+		if !isNameSpaceAttr(attrPtr) {
+			node.Hash = crc32.Update(node.Hash, crc32c, []byte(cfg.mapNamespace(attrSpace(attrPtr))))
+			node.Hash = crc32.Update(node.Hash, crc32c, []byte(cfg.nameKey(attrName(attrPtr))))
+			attrNames := append(names[:len(names):len(names)], attrPrefix+attrName(attrPtr))
+			node.Hash = crc32.Update(node.Hash, crc32c, []byte(cfg.hashedValue(cfg.comparedValue(node, attrNames, attrValue(attrPtr)))))
+		}
+	}
+}
@@ -63,8 +63,113 @@ func TestHashCodeCaching(t *testing.T) {
 
 	node := parseNode{XMLName: xml.Name{Space: "spc", Local: "name"}}
 	assertT.Equal(uint32(0), node.Hash)
-	hash := node.hashCode()
+	hash := node.hashCode(&config{})
 	assertT.Equal(hash, node.Hash)
 
-	assertT.Equal(hash, node.hashCode())
+	assertT.Equal(hash, node.hashCode(&config{}))
+}
+
+func TestUnorderedHashCode(t *testing.T) {
+	assertT := assert.New(t)
+
+	cfg := &config{unorderedAll: true}
+	root1, _ := parseXMLEx(`<a><b/><c/></a>`, cfg)
+	root2, _ := parseXMLEx(`<a><c/><b/></a>`, cfg)
+	assertT.Equal(root1.Hash, root2.Hash)
+}
+
+func TestCaseInsensitiveHashCode(t *testing.T) {
+	assertT := assert.New(t)
+
+	cfg, _ := createConfig([]Option{WithCaseInsensitiveNames(), WithCaseInsensitiveValues("//b/@*")})
+	root1, _ := parseXMLEx(`<A><b C="X">t<d/>u</b></A>`, cfg)
+	root2, _ := parseXMLEx(`<a><b c="x">t<D/>u</b></a>`, cfg)
+	assertT.Equal(root1.Hash, root2.Hash)
+
+	root2, _ = parseXMLEx(`<a><b c="x">T<D/>u</b></a>`, cfg)
+	assertT.NotEqual(root1.Hash, root2.Hash)
+}
+
+func TestExclusion(t *testing.T) {
+	assertT := assert.New(t)
+
+	cfg := newConfig()
+	cfg.excludedPaths, _ = compilePathPatterns([]string{"/a/b", "//@ts"})
+	root1, _ := parseXMLEx(`<a ts="1"><b>1</b><c ts="1" id="x"><b/></c></a>`, cfg)
+	root2, _ := parseXMLEx(`<a ts="2"><c ts="2" id="x"><b/></c><b>2</b></a>`, cfg)
+
+	assertT.Equal(0, len(root1.Attrs))
+	assertT.Equal(1, len(root1.Children))
+	assertT.Equal("c[id=x]", root1.Children[0].String())
+	assertT.Equal(1, len(root1.Children[0].Children))
+	assertT.Equal(root1, root1.Children[0].Parent)
+	assertT.Equal(root1.Hash, root2.Hash)
+}
+
+func TestTextSegments(t *testing.T) {
+	assertT := assert.New(t)
+
+	root1, _ := parseXML(`<p>a<b/>c</p>`)
+	root2, _ := parseXML(`<p>ac<b/></p>`)
+	assertT.Equal([]string{"a", "c"}, root1.Texts)
+	assertT.Equal([]string{"ac", ""}, root2.Texts)
+	assertT.Equal(root1.CharData, root2.CharData)
+	assertT.NotEqual(root1.Hash, root2.Hash)
+
+	cfg := newConfig()
+	cfg.excludedPaths, _ = compilePathPatterns([]string{"/p/b"})
+	root3, _ := parseXMLEx(`<p>a<b/>b<c/>c<b/>d</p>`, cfg)
+	assertT.Equal([]string{"ab", "cd"}, root3.Texts)
+
+	assertT.Equal([]string{"x", "", ""}, (&parseNode{CharData: "x", Children: make([]parseNode, 2)}).textSegments())
+}
+
+func TestMarkup(t *testing.T) {
+	assertT := assert.New(t)
+
+	root, err := parseXML(`<?xml version="1.0"?><!DOCTYPE a SYSTEM "a.dtd"><!--c1--><?pi x?><a><!--c2--><![CDATA[t]]></a><!--c3--><b><!--c4--></b><?pi y?>`)
+	assertT.Nil(err)
+	assertT.Equal("a SYSTEM \"a.dtd\"", root.Prolog.Doctype)
+	assertT.Equal([]string{"c1"}, root.Prolog.Comments)
+	assertT.Equal([]string{"pi x"}, root.Prolog.ProcInsts)
+	assertT.Equal([]string{"c2"}, root.Comments)
+	assertT.Equal([]string{"c3"}, root.Epilog.Comments)
+	assertT.Equal([]string{"pi y"}, root.Epilog.ProcInsts)
+	assertT.Equal("t", root.CharData)
+
+	root, err = parseXML(`<a/><b`)
+	assertT.Nil(err)
+	assertT.Empty(root.Epilog.Comments)
+
+	cfg := newConfig()
+	cfg.distinctCData = true
+	root, _ = parseXMLEx(`<a>x<![CDATA[<y>]]></a>`, cfg)
+	assertT.Equal("x<![CDATA[<y>]]>", root.CharData)
+}
+
+func TestSourcePrefix(t *testing.T) {
+	assertT := assert.New(t)
+
+	assertT.Equal("c", sourcePrefix(`c:item id="1">`))
+	assertT.Equal("", sourcePrefix(`item xmlns:c="urn:c">`))
+	assertT.Equal("", sourcePrefix(`item/>`))
+
+	root, _ := parseXML(`<c:a xmlns:c="urn:c"><b/></c:a>`)
+	assertT.Equal("c", root.Prefix)
+	assertT.Equal("", root.Children[0].Prefix)
+
+	root, _ = parseXML(`<a xmlns:c="urn:c" c:x="1"  y='c:2'/>`)
+	assertT.Equal([]string{"xmlns", "c", ""}, root.AttrPrefixes)
+	assertT.Equal("c", root.attrSourcePrefix(&root.Attrs[1]))
+}
+
+func TestPositions(t *testing.T) {
+	assertT := assert.New(t)
+
+	root, _ := parseXML(xmlString1)
+	assertT.Equal(Position{Line: 2, Column: 1, Offset: 1}, root.Pos)
+	assertT.Equal([]Position{{Line: 2, Column: 7, Offset: 7}}, root.AttrPos)
+	assertT.Equal(Position{Line: 3, Column: 5, Offset: 24}, root.Children[0].Pos)
+	assertT.Equal(Position{Line: 7, Column: 5, Offset: 139}, root.Children[4].Pos)
+	assertT.Equal("Tove", string(root.Children[0].Content))
 }
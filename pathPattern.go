@@ -0,0 +1,137 @@
+package xmlcomparator
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	anyName    = "*"
+	anyPath    = "**"
+	attrPrefix = "@"
+)
+
+// Compiled XPath-like pattern for selecting nodes.
+//
+// Pattern elements are node names separated by slashes. A pattern that does not start with slash matches at any depth.
+// Special elements:
+//   - `*` - any single node name
+//   - `**` or empty element (as in `//`) - any number of nodes, including none
+//   - `@name` - attribute of the node, allowed only as the last element; `@*` matches any attribute
+type pathPattern struct {
+	source   string
+	segments []string
+}
+
+// Compiles path pattern.
+//   - pattern - pattern string like "/order/item", "//item/@id" or "book"
+//
+// Returns: compiled pattern or error if the pattern is malformed
+func compilePathPattern(pattern string) (*pathPattern, error) {
+	if strings.TrimSpace(pattern) == "" {
+		return nil, errors.New("empty path pattern")
+	}
+
+	path := pattern
+	if strings.HasPrefix(path, "/") {
+		path = path[1:]
+	} else {
+		path = anyPath + "/" + path
+	}
+
+	segments := splitSegments(path)
+	if err := validateSegments(segments); err != nil {
+		return nil, err
+	}
+
+	return &pathPattern{source: pattern, segments: segments}, nil
+}
+
+// Splits the path into segments; empty segments and adjacent "any path" segments are merged into one "any path".
+func splitSegments(path string) []string {
+	segments := make([]string, 0)
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "" {
+			segment = anyPath
+		}
+		if segment == anyPath && len(segments) > 0 && segments[len(segments)-1] == anyPath {
+			continue
+		}
+		segments = append(segments, segment)
+	}
+	return segments
+}
+
+// Checks that an attribute segment, if any, is the last one and has a name.
+func validateSegments(segments []string) error {
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, attrPrefix) {
+			continue
+		}
+		if i != len(segments)-1 {
+			return errors.New("attribute is not the last element")
+		}
+		if len(segment) == len(attrPrefix) {
+			return errors.New("missing attribute name")
+		}
+	}
+	return nil
+}
+
+// Checks if the pattern matches the node.
+func (pattern *pathPattern) matchesNode(node *parseNode) bool {
+	return pattern.matches(node.names())
+}
+
+// Checks if the pattern matches the path given as a list of names from the root to the node.
+// The last name can be an attribute one prefixed with `@`.
+func (pattern *pathPattern) matches(names []string) bool {
+	return matchSegments(pattern.segments, names)
+}
+
+func matchSegments(segments []string, names []string) bool {
+	if len(segments) == 0 {
+		return len(names) == 0
+	}
+
+	if segments[0] == anyPath {
+		for i := 0; i <= len(names); i++ {
+			if matchSegments(segments[1:], names[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(names) == 0 || !matchSegment(segments[0], names[0]) {
+		return false
+	}
+	return matchSegments(segments[1:], names[1:])
+}
+
+func matchSegment(segment string, name string) bool {
+	isAttrSegment := strings.HasPrefix(segment, attrPrefix)
+	if isAttrSegment != strings.HasPrefix(name, attrPrefix) {
+		return false
+	}
+	if isAttrSegment {
+		segment = segment[len(attrPrefix):]
+		name = name[len(attrPrefix):]
+	}
+	return segment == anyName || segment == name
+}
+
+// Checks if any of patterns matches the node.
+func anyMatchesNode(patterns []*pathPattern, node *parseNode) bool {
+	return len(patterns) != 0 && anyMatches(patterns, node.names())
+}
+
+// Checks if any of patterns matches the path given as a list of names.
+func anyMatches(patterns []*pathPattern, names []string) bool {
+	for _, pattern := range patterns {
+		if pattern.matches(names) {
+			return true
+		}
+	}
+	return false
+}
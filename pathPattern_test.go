@@ -0,0 +1,79 @@
+package xmlcomparator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPathPatternCompilation(t *testing.T) {
+	assertT := assert.New(t)
+
+	tests := []struct {
+		pattern  string
+		segments []string
+	}{
+		{"/a/b", []string{"a", "b"}},
+		{"b", []string{"**", "b"}},
+		{"//b/@id", []string{"**", "b", "@id"}},
+		{"/a/**/**/b", []string{"a", "**", "b"}},
+		{"/a//*", []string{"a", "**", "*"}},
+	}
+
+	for _, tt := range tests {
+		pattern, err := compilePathPattern(tt.pattern)
+		assertT.Nil(err)
+		assertT.Equal(tt.segments, pattern.segments, tt.pattern)
+	}
+}
+
+func TestInvalidPathPatterns(t *testing.T) {
+	assertT := assert.New(t)
+
+	for _, invalid := range []string{"", " ", "/a/@b/c", "/a/@"} {
+		pattern, err := compilePathPattern(invalid)
+		assertT.Nil(pattern)
+		assertT.NotNil(err, invalid)
+	}
+}
+
+func TestPathPatternMatching(t *testing.T) {
+	assertT := assert.New(t)
+
+	tests := []struct {
+		pattern string
+		names   []string
+		matches bool
+	}{
+		{"/a/b", []string{"a", "b"}, true},
+		{"/a/b", []string{"a", "b", "c"}, false},
+		{"/a/b", []string{"x", "a", "b"}, false},
+		{"b", []string{"x", "a", "b"}, true},
+		{"/a/*", []string{"a", "c"}, true},
+		{"/a/*", []string{"a"}, false},
+		{"/a//c", []string{"a", "c"}, true},
+		{"/a//c", []string{"a", "b", "b", "c"}, true},
+		{"/a/b/@id", []string{"a", "b", "@id"}, true},
+		{"/a/b/@*", []string{"a", "b", "@id"}, true},
+		{"/a/b/*", []string{"a", "b", "@id"}, false},
+		{"/a/b/@id", []string{"a", "b", "id"}, false},
+		{"/", []string{"a", "b"}, true},
+	}
+
+	for _, tt := range tests {
+		pattern, _ := compilePathPattern(tt.pattern)
+		assertT.Equal(tt.matches, pattern.matches(tt.names), "%s vs %v", tt.pattern, tt.names)
+	}
+}
+
+func TestNodeMatching(t *testing.T) {
+	assertT := assert.New(t)
+
+	root, _ := parseXML(xmlString2)
+	pattern, _ := compilePathPattern("/root/birds/p")
+
+	assertT.False(pattern.matchesNode(root))
+	assertT.True(pattern.matchesNode(&root.Children[1].Children[0]))
+	assertT.False(pattern.matchesNode(&root.Children[0].Children[0]))
+	assertT.True(anyMatchesNode([]*pathPattern{pattern}, &root.Children[1].Children[1]))
+}
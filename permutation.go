@@ -0,0 +1,78 @@
+package xmlcomparator
+
+import (
+	"sort"
+)
+
+// Moved element of a permuted sequence
+type moveT struct {
+	from int // index in the first sequence
+	to   int // index in the second sequence
+}
+
+// Maps elements of the first sequence to positions of equal elements in the second one.
+// Repeated elements are matched in the order of their appearance.
+//   - a, b - sequences that are permutations of each other
+//
+// Returns: slice where the value at index `i` is the position of `a[i]` in `b`
+func matchPermutation[T comparable](a, b []T) []int {
+	positions := make(map[T][]int, len(b))
+	for j := range b {
+		positions[b[j]] = append(positions[b[j]], j)
+	}
+
+	perm := make([]int, len(a))
+	for i := range a {
+		queue := positions[a[i]]
+		perm[i] = queue[0]
+		positions[a[i]] = queue[1:]
+	}
+	return perm
+}
+
+// Finds the minimal set of elements that need to be moved to turn one sequence into another.
+// Elements that are not moved form the longest increasing subsequence of the permutation.
+//   - perm - permutation as returned by `matchPermutation`
+//
+// Returns: list of moves ordered by the index in the first sequence
+func minimalMoves(perm []int) []moveT {
+	stays := longestIncreasingSubsequence(perm)
+
+	moves := make([]moveT, 0, len(perm)-len(stays))
+	for i := range perm {
+		if _, ok := stays[i]; !ok {
+			moves = append(moves, moveT{from: i, to: perm[i]})
+		}
+	}
+	return moves
+}
+
+// Patience algorithm for longest increasing subsequence - O(N log N)
+//
+// Returns: set of indices of the elements in the subsequence
+func longestIncreasingSubsequence(seq []int) map[int]void {
+	tails := make([]int, 0, len(seq)) // indices of the smallest tails of subsequences with length `k+1`
+	prevs := make([]int, len(seq))    // back references to restore the subsequence
+
+	for i := range seq {
+		k := sort.Search(len(tails), func(k int) bool { return seq[tails[k]] >= seq[i] })
+		if k > 0 {
+			prevs[i] = tails[k-1]
+		} else {
+			prevs[i] = -1
+		}
+		if k == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[k] = i
+		}
+	}
+
+	ret := make(map[int]void, len(tails))
+	if len(tails) > 0 {
+		for i := tails[len(tails)-1]; i >= 0; i = prevs[i] {
+			ret[i] = empty
+		}
+	}
+	return ret
+}
@@ -0,0 +1,33 @@
+package xmlcomparator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchPermutation(t *testing.T) {
+	assertT := assert.New(t)
+
+	assertT.Equal([]int{}, matchPermutation([]rune{}, []rune{}))
+	assertT.Equal([]int{2, 0, 1}, matchPermutation([]rune("abc"), []rune("bca")))
+	assertT.Equal([]int{0, 2, 3, 1}, matchPermutation([]rune("abba"), []rune("aabb")))
+}
+
+func TestMinimalMoves(t *testing.T) {
+	assertT := assert.New(t)
+
+	assertT.Equal([]moveT{}, minimalMoves([]int{}))
+	assertT.Equal([]moveT{}, minimalMoves([]int{0, 1, 2}))
+	assertT.Equal([]moveT{{from: 3, to: 0}}, minimalMoves([]int{1, 2, 3, 0}))
+	assertT.Equal([]moveT{{from: 0, to: 3}}, minimalMoves([]int{3, 0, 1, 2}))
+	assertT.Equal([]moveT{{from: 0, to: 2}, {from: 1, to: 1}}, minimalMoves([]int{2, 1, 0}))
+	assertT.Equal(2, len(minimalMoves([]int{4, 0, 1, 5, 2, 3})))
+}
+
+func TestLongestIncreasingSubsequence(t *testing.T) {
+	assertT := assert.New(t)
+
+	assertT.Equal(map[int]void{}, longestIncreasingSubsequence([]int{}))
+	assertT.Equal(map[int]void{0: empty, 2: empty, 3: empty}, longestIncreasingSubsequence([]int{0, 3, 1, 2}))
+}
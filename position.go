@@ -0,0 +1,100 @@
+package xmlcomparator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Position in the XML source. Zero value means unknown position.
+type Position struct {
+	Line   int // 1-based line number
+	Column int // 1-based column number in characters
+	Offset int // 0-based byte offset
+}
+
+func (pos Position) String() string {
+	return fmt.Sprintf("%d:%d", pos.Line, pos.Column)
+}
+
+// Converts byte offsets to line and column positions.
+type lineIndex struct {
+	source     string
+	lineStarts []int
+	last       Position // the last provided position; decoding asks for increasing offsets
+}
+
+func createLineIndex(source string) *lineIndex {
+	lineStarts := []int{0}
+	for i := 0; i < len(source); i++ {
+		if source[i] == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	return &lineIndex{source: source, lineStarts: lineStarts}
+}
+
+// Provides position for the byte offset.
+// Characters are counted from the last position on the same line, so long lines are scanned once.
+func (index *lineIndex) position(offset int) Position {
+	line := sort.Search(len(index.lineStarts), func(i int) bool { return index.lineStarts[i] > offset })
+	start, column := index.lineStarts[line-1], 1
+	if index.last.Line == line && index.last.Offset <= offset {
+		start, column = index.last.Offset, index.last.Column
+	}
+	column += utf8.RuneCountInString(index.source[start:offset])
+	index.last = Position{Line: line, Column: column, Offset: offset}
+	return index.last
+}
+
+// Finds offsets of attributes in the start tag.
+//   - startTag - start tag text, like `<a x="1" y='2'>`
+//   - offset - offset of the start tag in the source
+//   - count - number of attributes in the tag
+//
+// Returns: offsets of the attribute names in the source
+func findAttrOffsets(startTag string, offset int, count int) []int {
+	offsets := make([]int, 0, count)
+
+	// Skipping the element name
+	i := strings.IndexAny(startTag, " \t\r\n/>")
+	for i >= 0 && len(offsets) < count {
+		i = skipSpaces(startTag, i)
+		if i >= len(startTag) || startTag[i] == '/' || startTag[i] == '>' {
+			break
+		}
+		offsets = append(offsets, offset+i)
+		i = skipAttribute(startTag, i)
+	}
+
+	return offsets
+}
+
+// Skips the attribute name and its quoted value.
+//   - i - index of the attribute name in the start tag
+//
+// Returns: index after the closing quote; -1 if the attribute isn't complete
+func skipAttribute(startTag string, i int) int {
+	eq := strings.IndexByte(startTag[i:], '=')
+	if eq < 0 {
+		return -1
+	}
+	i = skipSpaces(startTag, i+eq+1)
+	if i >= len(startTag) {
+		return -1
+	}
+	closing := strings.IndexByte(startTag[i+1:], startTag[i])
+	if closing < 0 {
+		return -1
+	}
+	return i + closing + 2
+}
+
+// Provides index of the first non-whitespace character starting from `i`.
+func skipSpaces(text string, i int) int {
+	for i < len(text) && strings.IndexByte(" \t\r\n", text[i]) >= 0 {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,35 @@
+package xmlcomparator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineIndex(t *testing.T) {
+	assertT := assert.New(t)
+
+	index := createLineIndex("ab\nцde\n\nf")
+	assertT.Equal(Position{Line: 1, Column: 1, Offset: 0}, index.position(0))
+	assertT.Equal(Position{Line: 1, Column: 3, Offset: 2}, index.position(2))
+	assertT.Equal(Position{Line: 2, Column: 1, Offset: 3}, index.position(3))
+	assertT.Equal(Position{Line: 2, Column: 2, Offset: 5}, index.position(5))
+	assertT.Equal(Position{Line: 4, Column: 1, Offset: 9}, index.position(9))
+	assertT.Equal("2:2", index.position(5).String())
+
+	// Positions on the same line, in any order
+	index = createLineIndex("<a>цц<b x='1'/>ц</a>")
+	assertT.Equal(Position{Line: 1, Column: 6, Offset: 7}, index.position(7))
+	assertT.Equal(Position{Line: 1, Column: 9, Offset: 10}, index.position(10))
+	assertT.Equal(Position{Line: 1, Column: 4, Offset: 3}, index.position(3))
+	assertT.Equal(Position{Line: 1, Column: 14, Offset: 15}, index.position(15))
+}
+
+func TestFindAttrOffsets(t *testing.T) {
+	assertT := assert.New(t)
+
+	assertT.Equal([]int{}, findAttrOffsets(`<a>`, 0, 0))
+	assertT.Equal([]int{}, findAttrOffsets(`<a/>`, 0, 0))
+	assertT.Equal([]int{13, 20}, findAttrOffsets(`<a x="1>" y = '"2'/>`, 10, 2))
+	assertT.Equal([]int{7, 17}, findAttrOffsets("<x:a\n\tx:y=\"1\"\n\t\tz=\"2\">", 1, 2))
+}
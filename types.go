@@ -2,31 +2,45 @@ package xmlcomparator
 
 import (
 	"encoding/xml"
+	"regexp"
 	"strconv"
 	"strings"
 )
 
+var qnamePattern = regexp.MustCompile(`^[\pL_][\pL\pN._-]*:[\pL_][\pL\pN._-]*$`)
+
+const (
+	xmlNamespace   = "http://www.w3.org/XML/1998/namespace"
+	localNameStep  = "*[local-name()='"
+	namespaceStep  = "' and namespace-uri()='"
+	localNameClose = "']"
+	documentPath   = "/"
+)
+
 // Creates a string representation of the XML path to the node.
 //
 // path elements are node names separated by slashes.
 //
-// Child element might have its index, unless it is the only child - handy for dealing with arrays.
+// Child element might have its index in the source, unless it is the only child - handy for dealing with arrays.
 func (node *parseNode) path() string {
+	return node.pathEx(CompactPathStyle)
+}
+
+// Creates a string representation of the XML path to the node in the given style.
+func (node *parseNode) pathEx(style PathStyle) string {
+	if style != CompactPathStyle {
+		return node.xpath(style)
+	}
+
 	path := make([]string, 0)
 	currNode := node
 
 	for currNode.Parent != nil {
-		siblings := currNode.Parent.Children
 		nodeName := nodeName(currNode)
-		if len(siblings) == 1 {
+		if len(currNode.Parent.ChildNames) == 1 {
 			path = append(path, "/"+nodeName)
 		} else {
-			for i := 0; i < len(siblings); i++ {
-				if siblings[i].Hash == currNode.Hash {
-					path = append(path, "/"+nodeName+"["+strconv.Itoa(i)+"]")
-					break
-				}
-			}
+			path = append(path, "/"+nodeName+"["+strconv.Itoa(currNode.Index)+"]")
 		}
 		currNode = currNode.Parent
 	}
@@ -41,6 +55,198 @@ func (node *parseNode) path() string {
 	return strings.Join(path, "")
 }
 
+// Creates XPath to the node.
+//
+// Child element has 1-based index among siblings with the same name, unless it is the only one.
+func (node *parseNode) xpath(style PathStyle) string {
+	steps := make([]string, 0)
+	for currNode := node; currNode != nil; currNode = currNode.Parent {
+		steps = append(steps, "/"+currNode.xpathStep(style))
+	}
+
+	// Reverse the path
+	size := len(steps)
+	for i := 0; i < size/2; i++ {
+		steps[i], steps[size-i-1] = steps[size-i-1], steps[i]
+	}
+
+	return strings.Join(steps, "")
+}
+
+func (node *parseNode) xpathStep(style PathStyle) string {
+	step := localNameStep + nodeName(node) + localNameClose
+	sameName := func(name xml.Name) bool { return name.Local == nodeName(node) }
+	if style == XPathStyle {
+		step = node.xpathName(node.XMLName, true)
+		sameName = func(name xml.Name) bool { return name == node.XMLName }
+	}
+	if node.Parent == nil {
+		return step
+	}
+
+	index, count := 0, 0
+	// Excluded siblings are counted as well
+	for i, name := range node.Parent.ChildNames {
+		if sameName(name) {
+			count++
+			if i == node.Index {
+				index = count
+			}
+		}
+	}
+	if count > 1 {
+		step += "[" + strconv.Itoa(index) + "]"
+	}
+	return step
+}
+
+// Creates XML path to the node attribute in the given style.
+func (node *parseNode) attrPath(attr *xml.Attr, style PathStyle) string {
+	switch style {
+	case XPathStyle:
+		return node.xpath(style) + "/" + attrPrefix + node.xpathName(attr.Name, false)
+	case LocalNameXPathStyle:
+		return node.xpath(style) + "/" + attrPrefix + localNameStep + attrName(attr) + localNameClose
+	default:
+		return node.path() + "/" + attrPrefix + attrName(attr)
+	}
+}
+
+// Provides the name for XPath step - qualified name, if the namespace has a prefix in the scope of the node,
+// or a predicate with the local name and namespace URI, like `*[local-name()='b' and namespace-uri()='urn:x']`,
+// since XPath can't select names in the default or undeclared namespace without a prefix.
+//   - isElement - whether the name is an element name that can be in the default namespace
+func (node *parseNode) xpathName(name xml.Name, isElement bool) string {
+	prefix := node.lookupPrefix(name.Space, isElement)
+	if name.Space == "" || prefix != "" && prefix != name.Space {
+		return node.qualifiedName(name, isElement)
+	}
+	return localNameStep + name.Local + namespaceStep + name.Space + localNameClose
+}
+
+// Provides the name with the namespace prefix declared in the scope of the node.
+//   - isElement - whether the name is an element name that can be in the default namespace
+func (node *parseNode) qualifiedName(name xml.Name, isElement bool) string {
+	if prefix := node.lookupPrefix(name.Space, isElement); prefix != "" {
+		return prefix + ":" + name.Local
+	}
+	return name.Local
+}
+
+// Finds the prefix bound to the namespace in the scope of the node.
+// Returns empty string for the default namespace and the namespace itself if it is not declared.
+func (node *parseNode) lookupPrefix(space string, isElement bool) string {
+	if space == "" {
+		return ""
+	}
+	if space == xmlNamespace {
+		return "xml"
+	}
+
+	for currNode := node; currNode != nil; currNode = currNode.Parent {
+		for i := range currNode.Attrs {
+			attr := &currNode.Attrs[i]
+			switch {
+			case attr.Value != space:
+			case attrSpace(attr) == "xmlns":
+				return attrName(attr)
+			case attrSpace(attr) == "" && attrName(attr) == "xmlns" && isElement:
+				return ""
+			}
+		}
+	}
+	return space
+}
+
+// Lists names of the nodes on the path from the root to the node.
+func (node *parseNode) names() []string {
+	depth := 0
+	for currNode := node; currNode != nil; currNode = currNode.Parent {
+		depth++
+	}
+
+	names := make([]string, depth)
+	for currNode := node; currNode != nil; currNode = currNode.Parent {
+		depth--
+		names[depth] = nodeName(currNode)
+	}
+	return names
+}
+
+// Finds position of the node attribute; falls back to the node position.
+func (node *parseNode) attrPosition(attr *xml.Attr) Position {
+	for i := range node.Attrs {
+		if node.Attrs[i].Name == attr.Name && i < len(node.AttrPos) {
+			return node.AttrPos[i]
+		}
+	}
+	return node.Pos
+}
+
+// Finds namespace prefix of the node attribute in the source, like "c" for `c:x="1"`.
+func (node *parseNode) attrSourcePrefix(attr *xml.Attr) string {
+	for i := range node.Attrs {
+		if node.Attrs[i].Name == attr.Name && i < len(node.AttrPrefixes) {
+			return node.AttrPrefixes[i]
+		}
+	}
+	return ""
+}
+
+// Resolves the prefix of the qualified name against namespace declarations in scope of the node.
+//
+// Returns: namespace URI, local name and `true` if the value is a qualified name with a declared prefix
+func (node *parseNode) resolveQName(value string) (string, string, bool) {
+	value = strings.TrimSpace(value)
+	if !qnamePattern.MatchString(value) {
+		return "", "", false
+	}
+
+	prefix, local, _ := strings.Cut(value, ":")
+	for currNode := node; currNode != nil; currNode = currNode.Parent {
+		for i := range currNode.Attrs {
+			if attr := &currNode.Attrs[i]; attrSpace(attr) == "xmlns" && attrName(attr) == prefix {
+				return attrValue(attr), local, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// Lists namespace declarations of the node, like "xmlns:c=urn:c".
+func (node *parseNode) namespaceDecls() []string {
+	decls := make([]string, 0)
+	for i := range node.Attrs {
+		if attr := &node.Attrs[i]; isNameSpaceAttr(attr) {
+			decls = append(decls, declPrefix(attr)+"="+attrValue(attr))
+		}
+	}
+	return decls
+}
+
+// Finds the value of the node key.
+//   - key - attribute name prefixed with `@` or name of the child element
+//   - nameKey - provides names to compare, like lower case ones
+//
+// Returns: the value and `true` if the node has the key
+func (node *parseNode) keyValue(key string, nameKey func(string) string) (string, bool) {
+	if strings.HasPrefix(key, attrPrefix) {
+		for i := range node.Attrs {
+			if nameKey(attrName(&node.Attrs[i])) == nameKey(key[len(attrPrefix):]) {
+				return attrValue(&node.Attrs[i]), true
+			}
+		}
+		return "", false
+	}
+
+	for i := range node.Children {
+		if nameKey(nodeName(&node.Children[i])) == nameKey(key) {
+			return strings.TrimSpace(node.Children[i].CharData), true
+		}
+	}
+	return "", false
+}
+
 // Converts XML node to a string that includes node name and attribites.
 func (node *parseNode) String() string {
 	attStr := ""
@@ -84,3 +290,11 @@ func attrValue(attr *xml.Attr) string {
 func isNameSpaceAttr(attr *xml.Attr) bool {
 	return attrSpace(attr) == "xmlns" || attrName(attr) == "xmlns"
 }
+
+// Provides the qualified name of the namespace declaration attribute, like "xmlns:c" or "xmlns".
+func declPrefix(attr *xml.Attr) string {
+	if attrSpace(attr) == "xmlns" {
+		return "xmlns:" + attrName(attr)
+	}
+	return "xmlns"
+}
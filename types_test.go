@@ -38,6 +38,72 @@ func TestXmlPathString(t *testing.T) {
 	assertT.Equal("/root/animal[2]/p", root.Children[2].Children[0].path())
 }
 
+func TestXmlPathOfIdenticalSiblings(t *testing.T) {
+	assertT := assert.New(t)
+
+	root, _ := parseXML("<a><b/><b/><c><d/></c><b/></a>")
+
+	assertT.Equal("/a/b[0]", root.Children[0].path())
+	assertT.Equal("/a/b[1]", root.Children[1].path())
+	assertT.Equal("/a/c[2]/d", root.Children[2].Children[0].path())
+	assertT.Equal("/a/b[3]", root.Children[3].path())
+}
+
+func TestXPathString(t *testing.T) {
+	assertT := assert.New(t)
+
+	root, _ := parseXML(`<a xmlns="urn:a" xmlns:c="urn:c"><b/><c:b c:x="1" xml:lang="en"/><b/><d/></a>`)
+
+	// Names in the default namespace can't be selected without a prefix
+	a := "/*[local-name()='a' and namespace-uri()='urn:a']"
+	assertT.Equal(a, root.pathEx(XPathStyle))
+	assertT.Equal(a+"/*[local-name()='b' and namespace-uri()='urn:a'][1]", root.Children[0].pathEx(XPathStyle))
+	assertT.Equal(a+"/c:b", root.Children[1].pathEx(XPathStyle))
+	assertT.Equal(a+"/*[local-name()='b' and namespace-uri()='urn:a'][2]", root.Children[2].pathEx(XPathStyle))
+	assertT.Equal(a+"/*[local-name()='d' and namespace-uri()='urn:a']", root.Children[3].pathEx(XPathStyle))
+	assertT.Equal(a+"/c:b/@c:x", root.Children[1].attrPath(&root.Children[1].Attrs[0], XPathStyle))
+	assertT.Equal(a+"/c:b/@xml:lang", root.Children[1].attrPath(&root.Children[1].Attrs[1], XPathStyle))
+
+	root2, _ := parseXML(`<a xmlns:c="urn:c"><b/><c:b/><b>1</b></a>`)
+	assertT.Equal("/a/b[2]", root2.Children[2].pathEx(XPathStyle))
+	assertT.Equal([]string{"a", "b", "@x"}, pathNames(a+"/*[local-name()='b' and namespace-uri()='http://x/y'][1]/@x"))
+
+	assertT.Equal("/*[local-name()='a']", root.pathEx(LocalNameXPathStyle))
+	assertT.Equal("/*[local-name()='a']/*[local-name()='b'][2]", root.Children[1].pathEx(LocalNameXPathStyle))
+	assertT.Equal("/*[local-name()='a']/*[local-name()='d']", root.Children[3].pathEx(LocalNameXPathStyle))
+	assertT.Equal("/*[local-name()='a']/*[local-name()='b'][2]/@*[local-name()='x']",
+		root.Children[1].attrPath(&root.Children[1].Attrs[0], LocalNameXPathStyle))
+
+	assertT.Equal("/a/b[1]/@x", root.Children[1].attrPath(&root.Children[1].Attrs[0], CompactPathStyle))
+}
+
+func TestNamespaceDecls(t *testing.T) {
+	assertT := assert.New(t)
+
+	root, _ := parseXML(`<a xmlns="urn:a" id="1" xmlns:c="urn:c"/>`)
+	assertT.Equal([]string{"xmlns=urn:a", "xmlns:c=urn:c"}, root.namespaceDecls())
+}
+
+func TestResolveQName(t *testing.T) {
+	assertT := assert.New(t)
+
+	root, _ := parseXML(`<a xmlns:c="urn:c" xmlns="urn:a"><b xmlns:c="urn:b"/></a>`)
+
+	space, local, ok := root.resolveQName(" c:Customer ")
+	assertT.True(ok)
+	assertT.Equal("urn:c", space)
+	assertT.Equal("Customer", local)
+	space, _, _ = root.Children[0].resolveQName("c:Customer")
+	assertT.Equal("urn:b", space)
+
+	_, _, ok = root.resolveQName("d:Customer")
+	assertT.False(ok)
+	_, _, ok = root.resolveQName("Customer")
+	assertT.False(ok)
+	_, _, ok = root.resolveQName("12:30")
+	assertT.False(ok)
+}
+
 func TestStringerInterface(t *testing.T) {
 	assertT := assert.New(t)
 
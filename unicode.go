@@ -0,0 +1,41 @@
+package xmlcomparator
+
+import (
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+	"golang.org/x/text/width"
+)
+
+// Unicode normalization of texts and attribute values
+type UnicodeForm int
+
+const (
+	NoNormalization UnicodeForm = iota // values are compared as is
+	NFC                                // canonical composition, like "é" and "é"
+	NFKC                               // compatibility composition, like "ﬁ" and "fi" or "①" and "1"
+)
+
+// Folding of texts and attribute values applied after Unicode normalization
+type UnicodeFolding int
+
+const (
+	FoldWidth UnicodeFolding = iota // full-width and half-width forms are folded, like "ＡＢＣ" and "ABC"
+	FoldCase                        // case is folded with Unicode rules, like "Straße" and "STRASSE"
+)
+
+// Provides the value normalized according to the Unicode form and foldings.
+func (cfg *config) normalizeUnicode(value string) string {
+	switch cfg.unicodeForm {
+	case NFC:
+		value = norm.NFC.String(value)
+	case NFKC:
+		value = norm.NFKC.String(value)
+	}
+	if cfg.foldWidth {
+		value = width.Fold.String(value)
+	}
+	if cfg.foldCase {
+		value = cases.Fold().String(value)
+	}
+	return value
+}
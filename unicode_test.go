@@ -0,0 +1,52 @@
+package xmlcomparator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeUnicode(t *testing.T) {
+	assertT := assert.New(t)
+
+	tests := []struct {
+		options []Option
+		value   string
+		result  string
+	}{
+		{[]Option{}, "Jose\u0301", "Jose\u0301"},
+		{[]Option{WithUnicodeNormalization(NFC)}, "Jose\u0301", "Jos\u00e9"},
+		{[]Option{WithUnicodeNormalization(NFC)}, "ﬁle", "ﬁle"},
+		{[]Option{WithUnicodeNormalization(NFKC)}, "ﬁle ①", "file 1"},
+		{[]Option{WithUnicodeNormalization(NFC, FoldWidth)}, "ＡＢＣ", "ABC"},
+		{[]Option{WithUnicodeNormalization(NFC, FoldCase)}, "Straße", "strasse"},
+		{[]Option{WithUnicodeNormalization(NoNormalization, FoldCase)}, "STRASSE", "strasse"},
+	}
+
+	for _, tt := range tests {
+		cfg, err := createConfig(tt.options)
+		assertT.Nil(err)
+		assertT.Equal(tt.result, cfg.normalizeUnicode(tt.value))
+	}
+}
+
+func TestUnicodeNormalization(t *testing.T) {
+	assertT := assert.New(t)
+
+	xmlSample1 := "<a><name lang=\"Jos\u00e9\">Jos\u00e9</name><name>Ren\u00e9e</name></a>"
+	xmlSample2 := "<a><name>Rene\u0301e</name><name lang=\"Jose\u0301\">Jose\u0301</name></a>"
+	assertT.Equal(4, len(CompareXmlStrings(xmlSample1, xmlSample2, false)))
+
+	// Hashes match, so children are paired correctly
+	diffs := ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithUnicodeNormalization(NFC)).GetMessages()
+	assertT.Equal([]string{"Children order differ for 2 nodes: name[0]->1, path='/a'"}, diffs)
+
+	diffs = ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithUnicodeNormalization(NFC), WithUnorderedChildren()).GetMessages()
+	assertT.Equal(emptyList, diffs)
+
+	xmlSample2 = "<a><name lang=\"JOSE\u0301\">\uff2a\uff4f\uff53\u00e9</name><name>Ren\u00e9e</name></a>"
+	diffs = ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithUnicodeNormalization(NFC, FoldWidth)).GetMessages()
+	assertT.Equal([]string{"Attribute values differ: 'Jos\u00e9' vs 'JOSE\u0301', path='/a/name[0]/@lang'"}, diffs)
+	diffs = ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithUnicodeNormalization(NFC, FoldWidth, FoldCase)).GetMessages()
+	assertT.Equal(emptyList, diffs)
+}
@@ -0,0 +1,74 @@
+package xmlcomparator
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Handling of whitespace in text content
+type WhitespaceMode int
+
+const (
+	TrimWhitespace       WhitespaceMode = iota // leading and trailing whitespace is ignored
+	StrictWhitespace                           // texts are compared as is
+	CollapseWhitespace                         // texts are trimmed and internal whitespace runs are replaced with a single space
+	NormalizeLineEndings                       // texts are trimmed and line endings `\r\n` and `\r`, like ones from `&#13;`, are replaced with `\n`
+	IgnoreWhitespace                           // all whitespace is ignored
+)
+
+// Provides the text of the node normalized according to the whitespace mode.
+// Texts of elements with `xml:space="preserve"` in the scope are not normalized.
+func (cfg *config) nodeText(node *parseNode) string {
+	if node.preservesSpace() {
+		return node.CharData
+	}
+	return normalizeWhitespace(node.CharData, cfg.whitespace)
+}
+
+// Provides text segments of the node normalized according to the whitespace mode.
+func (cfg *config) textSegments(node *parseNode) []string {
+	segments := node.textSegments()
+	if node.preservesSpace() {
+		return segments
+	}
+
+	texts := make([]string, len(segments))
+	for i := range segments {
+		texts[i] = normalizeWhitespace(segments[i], cfg.whitespace)
+	}
+	return texts
+}
+
+// Checks if `xml:space="preserve"` applies to the node.
+func (node *parseNode) preservesSpace() bool {
+	for currNode := node; currNode != nil; currNode = currNode.Parent {
+		for i := range currNode.Attrs {
+			attr := &currNode.Attrs[i]
+			if attrSpace(attr) == xmlNamespace && attrName(attr) == "space" {
+				return attrValue(attr) == "preserve"
+			}
+		}
+	}
+	return false
+}
+
+func normalizeWhitespace(text string, mode WhitespaceMode) string {
+	switch mode {
+	case StrictWhitespace:
+		return text
+	case CollapseWhitespace:
+		return strings.Join(strings.Fields(text), " ")
+	case NormalizeLineEndings:
+		// The parser normalizes literal line endings, but not character references
+		return strings.ReplaceAll(strings.ReplaceAll(strings.TrimSpace(text), "\r\n", "\n"), "\r", "\n")
+	case IgnoreWhitespace:
+		return strings.Map(func(r rune) rune {
+			if unicode.IsSpace(r) {
+				return -1
+			}
+			return r
+		}, text)
+	default:
+		return strings.TrimSpace(text)
+	}
+}
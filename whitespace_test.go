@@ -0,0 +1,42 @@
+package xmlcomparator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeWhitespace(t *testing.T) {
+	assertT := assert.New(t)
+
+	text := " a \r\n b\t\rc  "
+	assertT.Equal("a \r\n b\t\rc", normalizeWhitespace(text, TrimWhitespace))
+	assertT.Equal(text, normalizeWhitespace(text, StrictWhitespace))
+	assertT.Equal("a b c", normalizeWhitespace(text, CollapseWhitespace))
+	assertT.Equal("a \n b\t\nc", normalizeWhitespace(text, NormalizeLineEndings))
+	assertT.Equal("abc", normalizeWhitespace(text, IgnoreWhitespace))
+}
+
+func TestLineEndingsComparison(t *testing.T) {
+	assertT := assert.New(t)
+
+	// Character references aren't normalized by the parser
+	xmlSample1 := "<a>\n  <b>x&#13;&#10;y</b>\n</a>"
+	xmlSample2 := "<a><b>x\r\ny</b></a>"
+	assertT.Equal(3, len(ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithWhitespaceMode(StrictWhitespace)).GetDiffs()))
+	assertT.Equal([]string{"Node texts differ: 'x\r\ny' vs 'x\ny', path='/a/b'"},
+		ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithWhitespaceMode(TrimWhitespace)).GetMessages())
+	assertT.Equal(emptyList, ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithWhitespaceMode(NormalizeLineEndings)).GetMessages())
+}
+
+func TestPreservedSpace(t *testing.T) {
+	assertT := assert.New(t)
+
+	root, _ := parseXML(`<a xml:space="preserve"><b> x </b><c xml:space="default"><d> y </d></c></a>`)
+	cfg := newConfig()
+
+	assertT.True(root.preservesSpace())
+	assertT.Equal(" x ", cfg.nodeText(&root.Children[0]))
+	assertT.False(root.Children[1].preservesSpace())
+	assertT.Equal("y", cfg.nodeText(&root.Children[1].Children[0]))
+}
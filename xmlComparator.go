@@ -2,7 +2,9 @@ package xmlcomparator
 
 import (
 	"encoding/xml"
+	"errors"
 	"math"
+	"math/big"
 	"regexp"
 	"slices"
 	"sort"
@@ -12,14 +14,60 @@ import (
 	"github.com/aknopov/handymaps/bimap"
 )
 
-const (
-	eps = 1.e-6
-)
-
 var numberPattern = regexp.MustCompile(`^[-+]?[0-9]*\.?[0-9]+([eE][-+]?[0-9]+)?$`)
 
 var hashComparator = func(x, y uint32) bool { return x < y }
-var attrComparator = func(x, y xml.Attr) bool { return attrName(&x) < attrName(&y) }
+var attrComparator = func(x, y xml.Attr) bool {
+	return attrName(&x) < attrName(&y) || attrName(&x) == attrName(&y) && attrSpace(&x) < attrSpace(&y)
+}
+
+// Reusable XML comparator.
+// It is configured once with options and can be used for many comparisons, including concurrent ones.
+type Comparator struct {
+	cfg *config
+}
+
+// Creates XML comparator.
+//   - options - comparison options
+//
+// Returns: comparator or error if options are invalid
+func NewComparator(options ...Option) (*Comparator, error) {
+	cfg, err := createConfig(options)
+	if err != nil {
+		return nil, err
+	}
+	return &Comparator{cfg: cfg}, nil
+}
+
+// Compares two XML strings.
+//   - sample1 - first XML string
+//   - sample2 - second XML string
+//
+// Returns:
+// A list of detected discrepancies
+func (comparator *Comparator) Compare(sample1 string, sample2 string) DiffRecorder {
+	cfg := comparator.cfg
+	diffRecorder := createDiffRecorder(cfg.ignoredDiscrepancies, cfg.ignoreRules)
+
+	root1, err := parseXMLEx(sample1, cfg)
+	if root1 == nil || err != nil {
+		diffRecorder.addDiff(parserError{diffLocation: diffLocation{pos1: errorPosition(err)}, text: "Can't parse the first sample: " + err.Error()})
+		return diffRecorder
+	}
+
+	root2, err := parseXMLEx(sample2, cfg)
+	if root2 == nil || err != nil {
+		diffRecorder.addDiff(parserError{diffLocation: diffLocation{pos2: errorPosition(err)}, text: "Can't parse the second sample: " + err.Error()})
+		return diffRecorder
+	}
+
+	if documentDifferent(root1, root2, diffRecorder, cfg) && cfg.stopOnFirst {
+		return diffRecorder
+	}
+	nodesDifferent(root1, root2, diffRecorder, cfg)
+
+	return diffRecorder
+}
 
 // Compares two XML strings.
 //   - sample1 - first XML string
@@ -49,99 +97,492 @@ func CompareXmlStringsEx(sample1 string, sample2 string, stopOnFirst bool, ignor
 //   - sample2 - second XML string
 //   - stopOnFirst - stop comparison on the first difference
 //   - ignoredDiscrepancies - list of regular expressions for ignored discrepancies
+//   - options - additional comparison options
 //
 // Returns:
 // A list of detected discrepancies
-func ComputeDifferences(sample1 string, sample2 string, stopOnFirst bool, ignoredDiscrepancies []string) DiffRecorder {
-	diffRecorder := createDiffRecorder(ignoredDiscrepancies)
-
-	root1, err := parseXML(sample1)
-	if root1 == nil || err != nil {
-		diffRecorder.addDiff(parserError{text: "Can't parse the first sample: " + err.Error()})
-		return diffRecorder
+func ComputeDifferences(sample1 string, sample2 string, stopOnFirst bool, ignoredDiscrepancies []string, options ...Option) DiffRecorder {
+	allOptions := make([]Option, 0, len(options)+2)
+	if stopOnFirst {
+		allOptions = append(allOptions, WithStopOnFirst())
 	}
+	allOptions = append(allOptions, WithIgnoredDiscrepancies(ignoredDiscrepancies...))
+	allOptions = append(allOptions, options...)
 
-	root2, err := parseXML(sample2)
-	if root2 == nil || err != nil {
-		diffRecorder.addDiff(parserError{text: "Can't parse the second sample: " + err.Error()})
+	comparator, err := NewComparator(allOptions...)
+	if err != nil {
+		diffRecorder := createDiffRecorder([]*regexp.Regexp{}, []*ignoreRule{})
+		diffRecorder.addDiff(parserError{text: "Invalid comparison options: " + err.Error()})
 		return diffRecorder
 	}
 
-	nodesDifferent(root1, root2, diffRecorder, stopOnFirst)
+	return comparator.Compare(sample1, sample2)
+}
+
+// Provides location of the discrepancy between two nodes.
+func locate(node1 *parseNode, node2 *parseNode, cfg *config) diffLocation {
+	return diffLocation{xmlPath1: node1.pathEx(cfg.pathStyle), xmlPath2: node2.pathEx(cfg.pathStyle), pos1: node1.Pos, pos2: node2.Pos}
+}
 
-	return diffRecorder
+// Provides location of the discrepancy between node attributes.
+//   - attr1, attr2 - compared attributes; `nil` if the attribute is absent in the sample
+func locateAttr(node1 *parseNode, node2 *parseNode, attr1 *xml.Attr, attr2 *xml.Attr, cfg *config) diffLocation {
+	loc := diffLocation{pos1: node1.Pos, pos2: node2.Pos}
+	if attr1 != nil {
+		loc.xmlPath1 = node1.attrPath(attr1, cfg.pathStyle)
+		loc.pos1 = node1.attrPosition(attr1)
+	}
+	if attr2 != nil {
+		loc.xmlPath2 = node2.attrPath(attr2, cfg.pathStyle)
+		loc.pos2 = node2.attrPosition(attr2)
+	}
+	return loc
 }
 
-func nodesDifferent(node1 *parseNode, node2 *parseNode, diffRecorder *diffRecorder, stopOnFirst bool) {
-	switch {
-	case nodeNamesDifferent(node1, node2, diffRecorder) && stopOnFirst:
-		return
-	case nodeSpacesDifferent(node1, node2, diffRecorder) && stopOnFirst:
-		return
-	case nodesTextDifferent(node1, node2, diffRecorder) && stopOnFirst:
-		return
-	case attributesDifferent(node1, node2, diffRecorder) && stopOnFirst:
-		return
-	case childrenDifferent(node1, node2, diffRecorder, stopOnFirst):
-		return
+// Provides position of the parsing error, if known.
+func errorPosition(err error) Position {
+	var syntaxErr *xml.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return Position{Line: syntaxErr.Line}
+	}
+	return Position{}
+}
+
+// Comparison of a node aspect that records discrepancies
+type nodeComparison func(*parseNode, *parseNode, *diffRecorder, *config) bool
+
+func nodesDifferent(node1 *parseNode, node2 *parseNode, diffRecorder *diffRecorder, cfg *config) {
+	// Children are compared the last
+	comparisons := [...]nodeComparison{
+		nodeNamesDifferent,
+		nodeSpacesDifferent,
+		namespaceDeclsDifferent,
+		nodesTextDifferent,
+		nodeMarkupDifferent,
+		attributesDifferent,
+		childrenDifferent,
+	}
+	for _, different := range comparisons {
+		if different(node1, node2, diffRecorder, cfg) && cfg.stopOnFirst {
+			return
+		}
 	}
 }
 
-func nodeNamesDifferent(node1 *parseNode, node2 *parseNode, diffRecorder *diffRecorder) bool {
+func nodeNamesDifferent(node1 *parseNode, node2 *parseNode, diffRecorder *diffRecorder, cfg *config) bool {
 	name1 := nodeName(node1)
 	name2 := nodeName(node2)
-	if name1 == name2 {
+	if cfg.nameKey(name1) == cfg.nameKey(name2) {
 		return false
 	}
 
-	diffRecorder.addDiff(createTextDiff(DiffName, name1, name2, node1.path()))
+	diffRecorder.addDiff(createTextDiff(DiffName, name1, name2, locate(node1, node2, cfg)))
 	return true
 }
 
-func nodeSpacesDifferent(node1 *parseNode, node2 *parseNode, diffRecorder *diffRecorder) bool {
+func nodeSpacesDifferent(node1 *parseNode, node2 *parseNode, diffRecorder *diffRecorder, cfg *config) bool {
 	space1 := nodeSpace(node1)
 	space2 := nodeSpace(node2)
-	if space1 == space2 || space1 == "" || space2 == "" {
+	// Source namespaces are reported
+	mapped1, mapped2 := cfg.mapNamespace(space1), cfg.mapNamespace(space2)
+	if mapped1 == mapped2 || mapped1 == "" || mapped2 == "" {
 		return false
 	}
 
 	if diffRecorder.areNamespacesNew(space1, space2) {
-		diffRecorder.addDiff(createTextDiff(DiffSpace, space1, space2, node1.path()))
+		diffRecorder.addDiff(createTextDiff(DiffSpace, space1, space2, locate(node1, node2, cfg)))
 	}
 	return true
 }
-func nodesTextDifferent(node1 *parseNode, node2 *parseNode, diffRecorder *diffRecorder) bool {
-	ownText1 := strings.TrimSpace(node1.CharData)
 
-	ownText2 := strings.TrimSpace(node2.CharData)
-	if ownText1 == ownText2 || areEqualNumbers(ownText1, ownText2) {
+// Compares prefixes of the nodes, their attributes and namespace declarations in lexical namespaces mode.
+// Declarations are paired by prefixes; absent ones are reported as empty.
+func namespaceDeclsDifferent(node1 *parseNode, node2 *parseNode, diffRecorder *diffRecorder, cfg *config) bool {
+	if !cfg.lexicalNamespaces {
 		return false
 	}
 
-	diffRecorder.addDiff(createTextDiff(DiffContent, ownText1, ownText2, node1.path()))
+	different := prefixesDifferent(node1, node2, diffRecorder, cfg)
+	if different && cfg.stopOnFirst {
+		return true
+	}
+	return declarationsDifferent(node1, node2, diffRecorder, cfg) || different
+}
+
+// Compares source prefixes of the nodes and their attributes.
+func prefixesDifferent(node1 *parseNode, node2 *parseNode, diffRecorder *diffRecorder, cfg *config) bool {
+	different := false
+	if node1.Prefix != node2.Prefix {
+		diffRecorder.addDiff(createTextDiff(DiffPrefixes, node1.Prefix, node2.Prefix, locate(node1, node2, cfg)))
+		different = true
+	}
+
+	// Attributes are paired by namespaces and local names
+	attrs1 := node1.extractAttributes()
+	attrs2 := node2.extractAttributes()
+	for i := range attrs1 {
+		if different && cfg.stopOnFirst {
+			return true
+		}
+		j := slices.IndexFunc(attrs2, func(attr xml.Attr) bool { return cfg.sameAttrName(&attr, &attrs1[i]) })
+		if j < 0 {
+			continue
+		}
+		if prefix1, prefix2 := node1.attrSourcePrefix(&attrs1[i]), node2.attrSourcePrefix(&attrs2[j]); prefix1 != prefix2 {
+			diffRecorder.addDiff(createTextDiff(DiffPrefixes, prefix1, prefix2, locateAttr(node1, node2, &attrs1[i], &attrs2[j], cfg)))
+			different = true
+		}
+	}
+	return different
+}
+
+// Compares namespace declarations paired by prefixes.
+func declarationsDifferent(node1 *parseNode, node2 *parseNode, diffRecorder *diffRecorder, cfg *config) bool {
+	different := false
+	decls1 := node1.namespaceDecls()
+	decls2 := node2.namespaceDecls()
+	prefix := func(decl string) string { return decl[:strings.IndexByte(decl, '=')] }
+	for _, decl1 := range decls1 {
+		if different && cfg.stopOnFirst {
+			return true
+		}
+		j := slices.IndexFunc(decls2, func(decl2 string) bool { return prefix(decl2) == prefix(decl1) })
+		switch {
+		case j < 0:
+			diffRecorder.addDiff(createTextDiff(DiffNamespaceDecls, decl1, "", locate(node1, node2, cfg)))
+			different = true
+		case cfg.mapDecl(decls2[j]) != cfg.mapDecl(decl1):
+			diffRecorder.addDiff(createTextDiff(DiffNamespaceDecls, decl1, decls2[j], locate(node1, node2, cfg)))
+			different = true
+		}
+	}
+	for _, decl2 := range decls2 {
+		if different && cfg.stopOnFirst {
+			return true
+		}
+		if !slices.ContainsFunc(decls1, func(decl1 string) bool { return prefix(decl1) == prefix(decl2) }) {
+			diffRecorder.addDiff(createTextDiff(DiffNamespaceDecls, "", decl2, locate(node1, node2, cfg)))
+			different = true
+		}
+	}
+	return different
+}
+
+func nodesTextDifferent(node1 *parseNode, node2 *parseNode, diffRecorder *diffRecorder, cfg *config) bool {
+	// Mixed content is compared segment by segment, aligned by children
+	if len(node1.Children) != 0 && len(node2.Children) != 0 && !cfg.isUnordered(node1) {
+		return textSegmentsDifferent(node1, node2, diffRecorder, cfg)
+	}
+
+	ownText1 := cfg.nodeText(node1)
+	ownText2 := cfg.nodeText(node2)
+	names := node1.names()
+	if areEqualValues(cfg.comparedValue(node1, names, ownText1), cfg.comparedValue(node2, names, ownText2), names, cfg) {
+		return false
+	}
+
+	diffRecorder.addDiff(createTextDiff(DiffContent, ownText1, ownText2, locate(node1, node2, cfg)))
 	return true
 }
 
-func areEqualNumbers(text1, text2 string) bool {
+// Compares texts between children paired by the edit script.
+// Texts around added or removed children are compared at once, so text moved between inline elements is still reported.
+func textSegmentsDifferent(node1 *parseNode, node2 *parseNode, diffRecorder *diffRecorder, cfg *config) bool {
+	segments1 := cfg.textSegments(node1)
+	segments2 := cfg.textSegments(node2)
+	// Children without texts between them don't need alignment
+	if isBlank(segments1) && isBlank(segments2) {
+		return false
+	}
+	names := node1.names()
+	anchors := append(alignChildren(node1, node2, cfg), coord{x: len(node1.Children), y: len(node2.Children)})
+
+	different := false
+	prev := coord{x: -1, y: -1}
+	for _, next := range anchors {
+		// Segment `i` precedes child `i`
+		text1 := strings.Join(segments1[prev.x+1:next.x+1], "")
+		text2 := strings.Join(segments2[prev.y+1:next.y+1], "")
+		if !areEqualValues(cfg.comparedValue(node1, names, text1), cfg.comparedValue(node2, names, text2), names, cfg) {
+			diffRecorder.addDiff(createSegmentDiff(text1, text2, sourceSegment(node1, prev.x), describeSegment(node1, prev.x, next.x), locate(node1, node2, cfg)))
+			different = true
+			if cfg.stopOnFirst {
+				break
+			}
+		}
+		prev = next
+	}
+	return different
+}
+
+// Checks if all text segments are empty.
+func isBlank(segments []string) bool {
+	for _, segment := range segments {
+		if segment != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Pairs children of two nodes as the children edit script does - identical ones and changed ones with the same name.
+//
+// Returns: pairs of children indices, increasing in both lists
+func alignChildren(node1 *parseNode, node2 *parseNode, cfg *config) []coord {
+	diffs := compareSequencesEx(node1.Children, node2.Children, func(a, b parseNode) bool { return a.Hash == b.Hash }, true, defaultMaxDiffs)
+
+	pairs := make([]coord, 0, len(diffs))
+	edits := make([]diffT[parseNode], 0, len(diffs))
+	for _, d := range diffs {
+		if d.t == diffSame {
+			pairs = append(pairs, coord{x: d.aIdx, y: d.bIdx})
+		} else {
+			edits = append(edits, d)
+		}
+	}
+	it := createMatchingElementsMap(edits, cfg.pairingName).Iterator()
+	for it.HasNext() {
+		i, j := it.Next()
+		pairs = append(pairs, coord{x: edits[i].aIdx, y: edits[j].aIdx})
+	}
+
+	// Crossing pairs can't delimit texts
+	slices.SortFunc(pairs, func(a, b coord) int { return a.x - b.x })
+	aligned := make([]coord, 0, len(pairs))
+	for _, pair := range pairs {
+		if len(aligned) == 0 || pair.y > aligned[len(aligned)-1].y {
+			aligned = append(aligned, pair)
+		}
+	}
+	return aligned
+}
+
+// Provides index of the text segment in the source - the one right after the preceding child.
+//   - from - index of the preceding child; -1 if there is none
+func sourceSegment(node *parseNode, from int) int {
+	if from < 0 {
+		return 0
+	}
+	return node.Children[from].Index + 1
+}
+
+// Describes position of the text between two children with their source indices, like "between b[0] and c[1]".
+//   - from - index of the preceding child; -1 if there is none
+//   - to - index of the following child; count of children if there is none
+func describeSegment(node *parseNode, from int, to int) string {
+	childName := func(i int) string {
+		return nodeName(&node.Children[i]) + "[" + strconv.Itoa(node.Children[i].Index) + "]"
+	}
+	switch {
+	case from < 0 && to >= len(node.Children):
+		return "around all children"
+	case from < 0:
+		return "before " + childName(to)
+	case to >= len(node.Children):
+		return "after " + childName(from)
+	default:
+		return "between " + childName(from) + " and " + childName(to)
+	}
+}
+
+// Compares markup outside of the root elements - document types, comments and processing instructions before and after them.
+// Discrepancies are reported with the document path.
+func documentDifferent(root1 *parseNode, root2 *parseNode, diffRecorder *diffRecorder, cfg *config) bool {
+	loc := diffLocation{xmlPath1: documentPath, xmlPath2: documentPath}
+	different := false
+	if cfg.compareDoctype && root1.Prolog.Doctype != root2.Prolog.Doctype {
+		diffRecorder.addDiff(createTextDiff(DiffDoctype, root1.Prolog.Doctype, root2.Prolog.Doctype, loc))
+		different = true
+	}
+	if !(different && cfg.stopOnFirst) {
+		different = markupDifferent(root1.Prolog, root2.Prolog, loc, diffRecorder, cfg) || different
+	}
+	if !(different && cfg.stopOnFirst) {
+		different = markupDifferent(root1.Epilog, root2.Epilog, loc, diffRecorder, cfg) || different
+	}
+	return different
+}
+
+// Compares comments and processing instructions of the nodes, if any of them is enabled.
+func nodeMarkupDifferent(node1 *parseNode, node2 *parseNode, diffRecorder *diffRecorder, cfg *config) bool {
+	if !cfg.compareComments && !cfg.compareProcInsts {
+		return false
+	}
+	return markupDifferent(node1, node2, locate(node1, node2, cfg), diffRecorder, cfg)
+}
+
+// Compares enabled kinds of markup - comments and processing instructions.
+//   - loc - location of discrepancies
+func markupDifferent(node1 *parseNode, node2 *parseNode, loc diffLocation, diffRecorder *diffRecorder, cfg *config) bool {
+	different := false
+	if cfg.compareComments {
+		normalize := func(comment string) string { return normalizeWhitespace(comment, cfg.whitespace) }
+		different = listsDifferent(DiffComments, node1.Comments, node2.Comments, normalize, loc, diffRecorder, cfg)
+	}
+	if cfg.compareProcInsts && !(different && cfg.stopOnFirst) {
+		normalize := func(procInst string) string { return procInst }
+		different = listsDifferent(DiffProcInsts, node1.ProcInsts, node2.ProcInsts, normalize, loc, diffRecorder, cfg) || different
+	}
+	return different
+}
+
+// Compares lists of node markup item by item; missing items are reported as empty.
+func listsDifferent(diffType DiffType, list1 []string, list2 []string, normalize func(string) string, loc diffLocation,
+	diffRecorder *diffRecorder, cfg *config) bool {
+	different := false
+	for i := 0; i < max(len(list1), len(list2)); i++ {
+		item1, item2 := "", ""
+		if i < len(list1) {
+			item1 = normalize(list1[i])
+		}
+		if i < len(list2) {
+			item2 = normalize(list2[i])
+		}
+		if item1 != item2 {
+			diffRecorder.addDiff(createTextDiff(diffType, item1, item2, loc))
+			different = true
+			if cfg.stopOnFirst {
+				break
+			}
+		}
+	}
+	return different
+}
+
+// Checks if values of attributes are equivalent.
+//   - names - names of the nodes on the path from the root to the element
+func areEqualAttrValues(node1 *parseNode, node2 *parseNode, attr1 *xml.Attr, attr2 *xml.Attr, names []string, cfg *config) bool {
+	names = append(names[:len(names):len(names)], attrPrefix+attrName(attr1))
+	return areEqualValues(cfg.comparedValue(node1, names, attr1.Value), cfg.comparedValue(node2, names, attr2.Value), names, cfg)
+}
+
+// Checks if texts or attribute values are equivalent.
+//   - names - names of the nodes on the path from the root, ending with the attribute name prefixed with `@`, if any
+func areEqualValues(value1 string, value2 string, names []string, cfg *config) bool {
+	switch {
+	case value1 == value2:
+		return true
+	case cfg.numericMode == FloatNumbers:
+		return areEqualNumbers(value1, value2, cfg.toleranceFor(names))
+	default:
+		return areEqualDecimals(value1, value2, cfg.numericMode == ScaledDecimalNumbers)
+	}
+}
+
+// Provides the canonical form of numeric value for hashing, so that equal numbers, like `1.0` and `1`, have the same hash.
+// Non-numeric values are returned as is.
+func (cfg *config) hashedValue(value string) string {
+	if !numberPattern.MatchString(value) {
+		return value
+	}
+
+	switch cfg.numericMode {
+	case FloatNumbers:
+		number, _ := strconv.ParseFloat(value, 64)
+		return strconv.FormatFloat(number, 'g', -1, 64)
+	default:
+		number, ok := new(big.Rat).SetString(value)
+		if !ok {
+			return value
+		}
+		if cfg.numericMode == ScaledDecimalNumbers {
+			return number.RatString() + "@" + strconv.Itoa(decimalScale(value))
+		}
+		return number.RatString()
+	}
+}
+
+// Checks if texts are numbers that are equal within absolute or relative tolerance.
+func areEqualNumbers(text1, text2 string, tolerance NumericTolerance) bool {
 	if numberPattern.MatchString(text1) && numberPattern.MatchString(text2) {
-		val1, _ := strconv.ParseFloat(text1, 32)
-		val2, _ := strconv.ParseFloat(text2, 32)
-		return math.Abs(val2-val1) <= eps*(math.Abs(val2)+math.Abs(val1)+eps)
+		val1, _ := strconv.ParseFloat(text1, 64)
+		val2, _ := strconv.ParseFloat(text2, 64)
+		delta := math.Abs(val2 - val1)
+		eps := tolerance.Relative
+		return delta <= tolerance.Absolute || delta <= eps*(math.Abs(val2)+math.Abs(val1)+eps)
 	}
 	return false
 }
 
-func attributesDifferent(node1 *parseNode, node2 *parseNode, diffRecorder *diffRecorder) bool {
+// Checks if texts are decimal numbers with exactly equal values.
+//   - sameScale - whether numbers should also have the same scale
+func areEqualDecimals(text1, text2 string, sameScale bool) bool {
+	if !numberPattern.MatchString(text1) || !numberPattern.MatchString(text2) {
+		return false
+	}
+	if sameScale && decimalScale(text1) != decimalScale(text2) {
+		return false
+	}
+
+	val1, ok1 := new(big.Rat).SetString(text1)
+	val2, ok2 := new(big.Rat).SetString(text2)
+	return ok1 && ok2 && val1.Cmp(val2) == 0
+}
+
+// Provides the count of decimal digits after the point adjusted by the exponent, like 2 for `1.50` and -1 for `15e1`.
+func decimalScale(text string) int {
+	mantissa, exponent, _ := strings.Cut(strings.ToLower(text), "e")
+	scale := 0
+	if idx := strings.IndexByte(mantissa, '.'); idx >= 0 {
+		scale = len(mantissa) - idx - 1
+	}
+	exp, _ := strconv.Atoi(exponent)
+	return scale - exp
+}
+
+func attributesDifferent(node1 *parseNode, node2 *parseNode, diffRecorder *diffRecorder, cfg *config) bool {
 	attrs1 := node1.extractAttributes()
 	attrs2 := node2.extractAttributes()
 	if slices.Equal(attrs1, attrs2) || slices.Equal(sorted(attrs1, attrComparator), sorted(attrs2, attrComparator)) {
 		return false
 	}
 
-	diffs := compareSequences(attrs1, attrs2, func(a, b xml.Attr) bool { return a == b })
-	diffRecorder.addDiff(createAttributeDiff(diffs, len(attrs1), len(attrs2), node1.path()))
+	// Changed and absent in the second sample...
+	different := changedAttributesDifferent(node1, node2, attrs1, attrs2, diffRecorder, cfg)
+	if different && cfg.stopOnFirst {
+		return true
+	}
+	// ... then absent in the first one
+	return addedAttributesDifferent(node1, node2, attrs1, attrs2, diffRecorder, cfg) || different
+}
 
-	return true
+// Compares attributes of the first node with the same attributes of the second one; absent ones are reported as well.
+func changedAttributesDifferent(node1 *parseNode, node2 *parseNode, attrs1 []xml.Attr, attrs2 []xml.Attr,
+	diffRecorder *diffRecorder, cfg *config) bool {
+	different := false
+	names := node1.names()
+	for i := range attrs1 {
+		j := slices.IndexFunc(attrs2, func(attr xml.Attr) bool { return cfg.sameAttrName(&attr, &attrs1[i]) })
+		switch {
+		case j < 0:
+			diffRecorder.addDiff(createAttributeDiff(&attrs1[i], nil, locateAttr(node1, node2, &attrs1[i], nil, cfg)))
+			different = true
+		case !areEqualAttrValues(node1, node2, &attrs1[i], &attrs2[j], names, cfg):
+			diffRecorder.addDiff(createAttributeDiff(&attrs1[i], &attrs2[j], locateAttr(node1, node2, &attrs1[i], &attrs2[j], cfg)))
+			different = true
+		}
+		if different && cfg.stopOnFirst {
+			return true
+		}
+	}
+	return different
+}
+
+// Finds attributes of the second node that are absent in the first one.
+func addedAttributesDifferent(node1 *parseNode, node2 *parseNode, attrs1 []xml.Attr, attrs2 []xml.Attr,
+	diffRecorder *diffRecorder, cfg *config) bool {
+	different := false
+	for j := range attrs2 {
+		if !slices.ContainsFunc(attrs1, func(attr xml.Attr) bool { return cfg.sameAttrName(&attr, &attrs2[j]) }) {
+			diffRecorder.addDiff(createAttributeDiff(nil, &attrs2[j], locateAttr(node1, node2, nil, &attrs2[j], cfg)))
+			different = true
+			if cfg.stopOnFirst {
+				break
+			}
+		}
+	}
+
+	return different
 }
 
 func (node *parseNode) extractAttributes() []xml.Attr {
@@ -155,7 +596,7 @@ func (node *parseNode) extractAttributes() []xml.Attr {
 	return attrs
 }
 
-func childrenDifferent(node1 *parseNode, node2 *parseNode, diffRecorder *diffRecorder, stopOnFirst bool) bool {
+func childrenDifferent(node1 *parseNode, node2 *parseNode, diffRecorder *diffRecorder, cfg *config) bool {
 	// Simple case - identical children by hash
 	hashes1 := extractChildHashes(node1)
 	hashes2 := extractChildHashes(node2)
@@ -163,28 +604,182 @@ func childrenDifferent(node1 *parseNode, node2 *parseNode, diffRecorder *diffRec
 		return false
 	}
 
+	if cfg.isUnordered(node1) {
+		return unorderedChildrenDifferent(node1, node2, diffRecorder, cfg)
+	}
+
 	// Simple case - permutation of children
 	if len(hashes1) == len(hashes2) {
 		sortedHashes1 := sorted(hashes1, hashComparator)
 		sortedHashes2 := sorted(hashes2, hashComparator)
 		if slices.Equal(sortedHashes1, sortedHashes2) {
-			diffRecorder.addDiff(createOrderDiff(len(hashes1), node1.path()))
-			// TODO Implement comparison and output of sorted children
+			diffRecorder.addDiff(createOrderDiff(len(hashes1), findMovedChildren(node1, node2, hashes1, hashes2), locate(node1, node2, cfg)))
 			return true
 		}
 	}
 
 	diffs := compareSequences(node1.Children, node2.Children, func(a, b parseNode) bool { return a.Hash == b.Hash })
+	useSourceIndices(diffs)
+	matchingdMap := createMatchingElementsMap(diffs, cfg.pairingName)
+
+	changed := make([]ChildEdit, 0, matchingdMap.Size())
+	it := matchingdMap.Iterator()
+	for it.HasNext() {
+		i, j := it.Next()
+		changed = append(changed, ChildEdit{Change: Changed, Name: cfg.matchingName(&diffs[i].e), Index1: diffs[i].aIdx, Index2: diffs[j].aIdx,
+			Path1: diffs[i].e.pathEx(cfg.pathStyle), Path2: diffs[j].e.pathEx(cfg.pathStyle), Position1: diffs[i].e.Pos, Position2: diffs[j].e.Pos})
+	}
+	diffRecorder.addDiff(createChildrenDiff(diffs, changed, cfg.matchingName, cfg.pathStyle, len(node1.Children), len(node2.Children), locate(node1, node2, cfg)))
+
+	// Recursion!
+	iterateMatchingNodes(matchingdMap, diffs, diffRecorder, cfg)
+
+	return true
+}
+
+// Compares children as unordered collections.
+// Identical children are paired first, then the rest are paired by the best match.
+func unorderedChildrenDifferent(node1 *parseNode, node2 *parseNode, diffRecorder *diffRecorder, cfg *config) bool {
+	pairs, unmatched1, unmatched2 := pairChildren(node1.Children, node2.Children, cfg)
+
+	diffs := make([]diffT[parseNode], 0, len(unmatched1)+len(unmatched2))
+	for _, i := range unmatched1 {
+		diffs = append(diffs, diffT[parseNode]{e: node1.Children[i], t: diffDelete})
+	}
+	for _, j := range unmatched2 {
+		diffs = append(diffs, diffT[parseNode]{e: node2.Children[j], t: diffAdd})
+	}
+	useSourceIndices(diffs)
 
-	diffRecorder.addDiff(createChildrenDiff(diffs, len(node1.Children), len(node2.Children), node1.path()))
+	if len(diffs) == 0 && len(pairs) == 0 {
+		return false
+	}
+
+	changed := make([]ChildEdit, len(pairs))
+	for i, pair := range pairs {
+		changed[i] = ChildEdit{Change: Changed, Name: cfg.matchingName(&node1.Children[pair.x]),
+			Index1: node1.Children[pair.x].Index, Index2: node2.Children[pair.y].Index,
+			Path1: node1.Children[pair.x].pathEx(cfg.pathStyle), Path2: node2.Children[pair.y].pathEx(cfg.pathStyle),
+			Position1: node1.Children[pair.x].Pos, Position2: node2.Children[pair.y].Pos}
+	}
+	diffRecorder.addDiff(createChildrenDiff(diffs, changed, cfg.matchingName, cfg.pathStyle, len(node1.Children), len(node2.Children), locate(node1, node2, cfg)))
 
-	matchingdMap := createMatchingElementsMap(diffs, nodeName)
 	// Recursion!
-	iterateMatchingNodes(matchingdMap, diffs, diffRecorder, stopOnFirst)
+	for _, pair := range pairs {
+		nodesDifferent(&node1.Children[pair.x], &node2.Children[pair.y], diffRecorder, cfg)
+	}
 
 	return true
 }
 
+// Replaces indices of added and deleted children with their indices in the source, so excluded siblings are counted.
+func useSourceIndices(diffs []diffT[parseNode]) {
+	for i := range diffs {
+		diffs[i].aIdx = diffs[i].e.Index
+		diffs[i].bIdx = diffs[i].e.Index
+	}
+}
+
+// Pairs children of two nodes regardless of their order.
+//
+// Returns: pairs of matched but different children, indices of unmatched children in the first and the second lists
+func pairChildren(children1 []parseNode, children2 []parseNode, cfg *config) ([]coord, []int, []int) {
+	matched1 := make([]bool, len(children1))
+	matched2 := make([]bool, len(children2))
+
+	// Identical children first
+	pairIdenticalChildren(children1, children2, matched1, matched2)
+	// Then the best matching ones with the same name and key
+	pairs := pairSimilarChildren(children1, children2, matched1, matched2, cfg)
+
+	return pairs, unmatchedIndices(matched1), unmatchedIndices(matched2)
+}
+
+// Marks children with equal hashes as matched in the order of the lists.
+func pairIdenticalChildren(children1 []parseNode, children2 []parseNode, matched1 []bool, matched2 []bool) {
+	positions := make(map[uint32][]int, len(children2))
+	for j := range children2 {
+		positions[children2[j].Hash] = append(positions[children2[j].Hash], j)
+	}
+	for i := range children1 {
+		if queue := positions[children1[i].Hash]; len(queue) > 0 {
+			matched1[i] = true
+			matched2[queue[0]] = true
+			positions[children1[i].Hash] = queue[1:]
+		}
+	}
+}
+
+// Pairs unmatched children with the same name and key by the best similarity score.
+//
+// Returns: pairs of indices of matched children
+func pairSimilarChildren(children1 []parseNode, children2 []parseNode, matched1 []bool, matched2 []bool, cfg *config) []coord {
+	pairs := make([]coord, 0)
+	for i := range children1 {
+		if matched1[i] {
+			continue
+		}
+		name1 := cfg.pairingName(&children1[i])
+		bestJ, bestScore := -1, -1
+		for j := range children2 {
+			if matched2[j] || name1 != cfg.pairingName(&children2[j]) {
+				continue
+			}
+			if score := similarity(&children1[i], &children2[j], cfg); score > bestScore {
+				bestJ, bestScore = j, score
+			}
+		}
+		if bestJ >= 0 {
+			matched1[i] = true
+			matched2[bestJ] = true
+			pairs = append(pairs, coord{x: i, y: bestJ})
+		}
+	}
+	return pairs
+}
+
+// Rough similarity score of two nodes - count of equal text, attributes and children.
+// Texts and attributes are equal if they are equivalent in comparison, like numbers or values in different case.
+func similarity(node1 *parseNode, node2 *parseNode, cfg *config) int {
+	score := 0
+	names := node1.names()
+	if areEqualValues(cfg.comparedValue(node1, names, cfg.nodeText(node1)), cfg.comparedValue(node2, names, cfg.nodeText(node2)), names, cfg) {
+		score++
+	}
+
+	attrs2 := node2.extractAttributes()
+	for _, attr1 := range node1.extractAttributes() {
+		if slices.ContainsFunc(attrs2, func(attr2 xml.Attr) bool {
+			return cfg.sameAttrName(&attr1, &attr2) && areEqualAttrValues(node1, node2, &attr1, &attr2, names, cfg)
+		}) {
+			score++
+		}
+	}
+
+	hashes2 := make(map[uint32]int, len(node2.Children))
+	for j := range node2.Children {
+		hashes2[node2.Children[j].Hash]++
+	}
+	for i := range node1.Children {
+		if hashes2[node1.Children[i].Hash] > 0 {
+			hashes2[node1.Children[i].Hash]--
+			score++
+		}
+	}
+
+	return score
+}
+
+func unmatchedIndices(matched []bool) []int {
+	ret := make([]int, 0)
+	for i := range matched {
+		if !matched[i] {
+			ret = append(ret, i)
+		}
+	}
+	return ret
+}
+
 func extractChildHashes(node *parseNode) []uint32 {
 	hashes := make([]uint32, len(node.Children))
 	for i := range node.Children {
@@ -193,11 +788,24 @@ func extractChildHashes(node *parseNode) []uint32 {
 	return hashes
 }
 
-func iterateMatchingNodes(matchingMap *bimap.BiMap[int, int], diffs []diffT[parseNode], diffRecorder *diffRecorder, stopOnFirst bool) {
+// Finds the minimal set of children that changed their positions.
+// Positions are reported as source indices of the children.
+func findMovedChildren(node1 *parseNode, node2 *parseNode, hashes1 []uint32, hashes2 []uint32) []ChildMove {
+	moves := minimalMoves(matchPermutation(hashes1, hashes2))
+
+	ret := make([]ChildMove, len(moves))
+	for i := range moves {
+		child1 := &node1.Children[moves[i].from]
+		ret[i] = ChildMove{Name: nodeName(child1), From: child1.Index, To: node2.Children[moves[i].to].Index}
+	}
+	return ret
+}
+
+func iterateMatchingNodes(matchingMap *bimap.BiMap[int, int], diffs []diffT[parseNode], diffRecorder *diffRecorder, cfg *config) {
 	it := matchingMap.Iterator()
 	for it.HasNext() {
 		i, j := it.Next()
-		nodesDifferent(&diffs[i].e, &diffs[j].e, diffRecorder, stopOnFirst)
+		nodesDifferent(&diffs[i].e, &diffs[j].e, diffRecorder, cfg)
 	}
 }
 
@@ -22,7 +22,7 @@ func TestInvalidStrings(t *testing.T) {
 func TestDifferentNames(t *testing.T) {
 	assertT := assert.New(t)
 
-	assertT.Equal([]string{"Node names differ: 'note' vs 'root', path='/note'"}, CompareXmlStrings(xmlString1, xmlString2, true))
+	assertT.Equal([]string{"Node names differ: 'note' vs 'root', path1='/note', path2='/root'"}, CompareXmlStrings(xmlString1, xmlString2, true))
 }
 
 func TestDifferentNameSpaces(t *testing.T) {
@@ -33,6 +33,40 @@ func TestDifferentNameSpaces(t *testing.T) {
 	assertT.Equal([]string{"Node namespaces differ: 'space1' vs 'space2', path='/a'"}, CompareXmlStrings(xmlSample1, xmlSample2, true))
 }
 
+func TestNamespaceMapping(t *testing.T) {
+	assertT := assert.New(t)
+
+	xmlSample1 := `<a xmlns="urn:api:v1" xmlns:x="urn:x:v1"><b x:id="1"/></a>`
+	xmlSample2 := `<a xmlns="urn:api:v2" xmlns:y="urn:x:v2"><b y:id="1"/></a>`
+	assertT.Equal([]string{
+		"Node namespaces differ: 'urn:api:v1' vs 'urn:api:v2', path='/a'",
+		"Attribute is absent in the second sample: 'id=1', namespace='urn:x:v1', path='/a/b/@id'",
+		"Attribute is absent in the first sample: 'id=1', namespace='urn:x:v2', path='/a/b/@id'",
+	}, CompareXmlStrings(xmlSample1, xmlSample2, false))
+
+	diffs := ComputeDifferences(xmlSample1, xmlSample2, false, emptyList,
+		WithNamespaceMapping("urn:api:v1", "urn:api:v2"), WithNamespaceMapping("urn:x:v1", "urn:x:v2")).GetMessages()
+	assertT.Equal(emptyList, diffs)
+
+	diffs = ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithIgnoredNamespaces()).GetMessages()
+	assertT.Equal(emptyList, diffs)
+
+	diffs = ComputeDifferences(xmlSample1, xmlSample2, false, emptyList,
+		WithNamespaceMapping("urn:api:v1", "urn:api:v2"), WithPathStyle(XPathStyle)).GetMessages()
+	// Paths keep source namespaces
+	b1 := "/*[local-name()='a' and namespace-uri()='urn:api:v1']/*[local-name()='b' and namespace-uri()='urn:api:v1']"
+	b2 := "/*[local-name()='a' and namespace-uri()='urn:api:v2']/*[local-name()='b' and namespace-uri()='urn:api:v2']"
+	assertT.Equal([]string{
+		"Attribute is absent in the second sample: 'id=1', namespace='urn:x:v1', path='" + b1 + "/@x:id'",
+		"Attribute is absent in the first sample: 'id=1', namespace='urn:x:v2', path='" + b2 + "/@y:id'",
+	}, diffs)
+
+	// Messages show source namespaces
+	diffs = ComputeDifferences(xmlSample1, xmlSample2, false, emptyList,
+		WithNamespaceMapping("urn:api:v1", "urn:api:v3"), WithNamespaceMapping("urn:x:v1", "urn:x:v2")).GetMessages()
+	assertT.Equal([]string{"Node namespaces differ: 'urn:api:v1' vs 'urn:api:v2', path='/a'"}, diffs)
+}
+
 func TestIgnoringNameSpacePrefixes(t *testing.T) {
 	assertT := assert.New(t)
 
@@ -41,15 +75,48 @@ func TestIgnoringNameSpacePrefixes(t *testing.T) {
 	assertT.Equal(emptyList, CompareXmlStrings(xmlSample1, xmlSample2, false))
 }
 
+func TestLexicalNamespaces(t *testing.T) {
+	assertT := assert.New(t)
+
+	xmlSample1 := `<X:a xmlns:X="space1"><b/><c/></X:a>`
+	xmlSample2 := `<a xmlns="space1"><b/><c/></a>`
+	diffs := ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithLexicalNamespaces()).GetDiffs()
+	assertT.Equal(3, len(diffs))
+	assertT.Equal(DiffPrefixes, diffs[0].GetType())
+	assertT.Equal("Namespace prefixes differ: 'X' vs '', path='/a'", diffs[0].DescribeDiff())
+	assertT.Equal(DiffNamespaceDecls, diffs[1].GetType())
+	assertT.Equal("Namespace declarations differ: 'xmlns:X=space1' vs '', path='/a'", diffs[1].DescribeDiff())
+	assertT.Equal("Namespace declarations differ: '' vs 'xmlns=space1', path='/a'", diffs[2].DescribeDiff())
+
+	xmlSample1 = `<a xmlns:c="urn:c"><b xmlns:c="urn:c"/><d xmlns:e="urn:e"/></a>`
+	xmlSample2 = `<a xmlns:c="urn:c"><b/><d xmlns:e="urn:e2"/></a>`
+	assertT.Equal([]string{
+		"Namespace declarations differ: 'xmlns:c=urn:c' vs '', path='/a/b[0]'",
+		"Namespace declarations differ: 'xmlns:e=urn:e' vs 'xmlns:e=urn:e2', path='/a/d[1]'",
+	}, ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithLexicalNamespaces()).GetMessages())
+
+	diffs = ComputeDifferences(`<X:a xmlns:X="space1"/>`, `<a xmlns="space1"/>`, true, emptyList, WithLexicalNamespaces()).GetDiffs()
+	assertT.Equal(1, len(diffs))
+
+	// Prefixes of attributes bound to the same namespace
+	xmlSample1 = `<a xmlns:p="urn:p" xmlns:q="urn:p"><b p:x="1" y="2"/></a>`
+	xmlSample2 = `<a xmlns:p="urn:p" xmlns:q="urn:p"><b q:x="1" y="2"/></a>`
+	assertT.Equal(emptyList, CompareXmlStrings(xmlSample1, xmlSample2, false))
+	diffs = ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithLexicalNamespaces()).GetDiffs()
+	assertT.Equal(1, len(diffs))
+	assertT.Equal(DiffPrefixes, diffs[0].GetType())
+	assertT.Equal("Namespace prefixes differ: 'p' vs 'q', path='/a/b/@x'", diffs[0].DescribeDiff())
+}
+
 func TestDifferentAttributes(t *testing.T) {
 	assertT := assert.New(t)
 
 	xmlSample1 := `<a attr1="12" attr2="xy"/>`
 	xmlSample2 := `<a attr2="xy"/>`
 	xmlSample3 := `<a attr1="12" attr2="ab"/>`
-	assertT.Equal([]string{"Attributes differ: counts 2 vs 1: attr1[0]:+1, path='/a'"},
+	assertT.Equal([]string{"Attribute is absent in the second sample: 'attr1=12', path='/a/@attr1'"},
 		CompareXmlStrings(xmlSample1, xmlSample2, false))
-	assertT.Equal([]string{"Attributes differ: 'attr2=xy' vs 'attr2=ab', path='/a'"},
+	assertT.Equal([]string{"Attribute values differ: 'xy' vs 'ab', path='/a/@attr2'"},
 		CompareXmlStrings(xmlSample1, xmlSample3, true))
 
 	xmlSample4 := `<X:a xmlns:X="space1"><b foo=""/><c/></X:a>`
@@ -57,7 +124,109 @@ func TestDifferentAttributes(t *testing.T) {
 	diffs := CompareXmlStrings(xmlSample4, xmlSample5, false)
 	assertT.Equal(2, len(diffs))
 	assertT.Equal("Node namespaces differ: 'space1' vs 'space2', path='/a'", diffs[0])
-	assertT.Equal("Attributes differ: 'foo=' vs 'foo=bar', path='/a/b[0]'", diffs[1])
+	assertT.Equal("Attribute values differ: '' vs 'bar', path='/a/b[0]/@foo'", diffs[1])
+}
+
+func TestAttributePaths(t *testing.T) {
+	assertT := assert.New(t)
+
+	xmlSample1 := `<order xmlns:c="urn:c"><item/><item c:currency="EUR" id="1"/></order>`
+	xmlSample2 := `<order xmlns:c="urn:c"><item/><item id="2" note="x"/></order>`
+	diffs := ComputeDifferences(xmlSample1, xmlSample2, false, emptyList).GetDiffs()
+	assertT.Equal(3, len(diffs))
+
+	assertT.Equal("Attribute is absent in the second sample: 'currency=EUR', namespace='urn:c', path='/order/item[1]/@currency'", diffs[0].DescribeDiff())
+	assertT.Equal("/order/item[1]/@currency", diffs[0].XmlPath1())
+	assertT.Equal("", diffs[0].XmlPath2())
+	assertT.Equal(Position{Line: 1, Column: 37, Offset: 36}, diffs[0].Position1())
+	assertT.Equal(Position{Line: 1, Column: 31, Offset: 30}, diffs[0].Position2())
+
+	assertT.Equal("Attribute values differ: '1' vs '2', path='/order/item[1]/@id'", diffs[1].DescribeDiff())
+	assertT.Equal(Position{Line: 1, Column: 37, Offset: 36}, diffs[1].Position2())
+
+	assertT.Equal("Attribute is absent in the first sample: 'note=x', path='/order/item[1]/@note'", diffs[2].DescribeDiff())
+	assertT.Equal("", diffs[2].XmlPath1())
+	assertT.Equal("/order/item[1]/@note", diffs[2].XmlPath())
+	assertT.Equal("/order/item[1]/@note", diffs[2].XmlPath2())
+}
+
+func TestNamespacedAttributes(t *testing.T) {
+	assertT := assert.New(t)
+
+	xmlSample1 := `<a xmlns:x="urn:x" xmlns:y="urn:y"><b x:id="1" y:id="2"/></a>`
+	xmlSample2 := `<a xmlns:x="urn:x" xmlns:y="urn:y"><b y:id="2" x:id="1"/></a>`
+	assertT.Equal(emptyList, CompareXmlStrings(xmlSample1, xmlSample2, false))
+
+	xmlSample2 = `<a xmlns:x="urn:x" xmlns:y="urn:y"><b y:id="1" x:id="2"/></a>`
+	assertT.Equal([]string{
+		"Attribute values differ: '1' vs '2', namespace='urn:x', path='/a/b/@id'",
+		"Attribute values differ: '2' vs '1', namespace='urn:y', path='/a/b/@id'",
+	}, CompareXmlStrings(xmlSample1, xmlSample2, false))
+
+	root1, _ := parseXML(`<b x:id="1" xmlns:x="urn:x"/>`)
+	root2, _ := parseXML(`<b y:id="1" xmlns:y="urn:y"/>`)
+	assertT.NotEqual(root1.Hash, root2.Hash)
+}
+
+func TestQNameValues(t *testing.T) {
+	assertT := assert.New(t)
+
+	xmlSample1 := `<a xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:ns1="urn:c">` +
+		`<b xsi:type="ns1:Customer"/><faultcode>ns1:Client</faultcode><c ref="ns1:x"/></a>`
+	xmlSample2 := `<a xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:c="urn:c">` +
+		`<b xsi:type="c:Customer"/><faultcode>c:Client</faultcode><c ref="c:x"/></a>`
+	assertT.Equal(3, len(CompareXmlStrings(xmlSample1, xmlSample2, false)))
+
+	diffs := ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithQNameValues()).GetMessages()
+	assertT.Equal(emptyList, diffs)
+
+	diffs = ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithQNameValues("//@type", "//faultcode")).GetMessages()
+	assertT.Equal([]string{"Attribute values differ: 'ns1:x' vs 'c:x', path='/a/c[2]/@ref'"}, diffs)
+
+	xmlSample2 = `<a xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:c="urn:d">` +
+		`<b xsi:type="c:Customer"/><faultcode>c:Client</faultcode><c ref="c:x"/></a>`
+	diffs = ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithQNameValues()).GetMessages()
+	assertT.Equal([]string{
+		"Attribute values differ: 'ns1:Customer' vs 'c:Customer', namespace='http://www.w3.org/2001/XMLSchema-instance', path='/a/b[0]/@type'",
+		"Node texts differ: 'ns1:Client' vs 'c:Client', path='/a/faultcode[1]'",
+		"Attribute values differ: 'ns1:x' vs 'c:x', path='/a/c[2]/@ref'",
+	}, diffs)
+}
+
+func TestCaseInsensitiveComparison(t *testing.T) {
+	assertT := assert.New(t)
+
+	xmlSample1 := `<Order ID="1"><CustomerID>c1</CustomerID><Status>PAID</Status><Note>Urgent</Note></Order>`
+	xmlSample2 := `<order id="1"><Note>urgent</Note><customerid>c1</customerid><status>paid</status></order>`
+	assertT.Equal(5, len(ComputeDifferences(xmlSample1, xmlSample2, false, emptyList).GetDiffs()))
+
+	diffs := ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithCaseInsensitiveNames(), WithUnorderedChildren()).GetMessages()
+	assertT.Equal([]string{
+		"Node texts differ: 'PAID' vs 'paid', path1='/Order/Status[1]', path2='/order/status[2]'",
+		"Node texts differ: 'Urgent' vs 'urgent', path1='/Order/Note[2]', path2='/order/Note[0]'",
+	}, diffs)
+
+	diffs = ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithCaseInsensitiveNames(), WithUnorderedChildren(),
+		WithCaseInsensitiveValues("//status", "//Status")).GetMessages()
+	assertT.Equal([]string{"Node texts differ: 'Urgent' vs 'urgent', path1='/Order/Note[2]', path2='/order/Note[0]'"}, diffs)
+
+	diffs = ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithCaseInsensitiveNames(), WithUnorderedChildren(),
+		WithCaseInsensitiveValues()).GetMessages()
+	assertT.Equal(emptyList, diffs)
+
+	// Key values are compared with case
+	diffs = ComputeDifferences(`<a><i id="A"/></a>`, `<a><I id="a"/></a>`, false, emptyList, WithCaseInsensitiveNames(), WithMatchKey("*", "@id")).GetMessages()
+	assertT.Equal([]string{"Children differ: counts 1 vs 1: i[@id='A'][0]:+1, I[@id='a'][0]:-1, path='/a'"}, diffs)
+	diffs = ComputeDifferences(`<a><i id="A"/></a>`, `<a><I id="A"/></a>`, false, emptyList, WithCaseInsensitiveNames(), WithMatchKey("*", "@id")).GetMessages()
+	assertT.Equal(emptyList, diffs)
+
+	// Structural changes are still reported
+	xmlSample2 = `<order id="1" ref="x"><customerid>c1</customerid><state>PAID</state></order>`
+	diffs = ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithCaseInsensitiveNames()).GetMessages()
+	assertT.Equal([]string{
+		"Attribute is absent in the first sample: 'ref=x', path='/order/@ref'",
+		"Children differ: counts 3 vs 2: Status[1]:+1, Note[2]:+1, state[1]:-1, path1='/Order', path2='/order'",
+	}, diffs)
 }
 
 func TestEqualWithDifferentAttributesOrder(t *testing.T) {
@@ -71,10 +240,47 @@ func TestEqualWithDifferentAttributesOrder(t *testing.T) {
 func TestDifferentCharData(t *testing.T) {
 	assertT := assert.New(t)
 
-	assertT.Equal([]string{"Node texts differ: '' vs 'Some text ...\n    \n\tmixed with elements', path='/note'"},
+	assertT.Equal([]string{"Node texts differ: '' vs 'Some text ...', segment='before to[0]', path='/note'"},
 		CompareXmlStrings(xmlString1, xmlMixed, true))
 }
 
+func TestMixedContent(t *testing.T) {
+	assertT := assert.New(t)
+
+	diffs := ComputeDifferences(`<p>a<b/>c<i/></p>`, `<p>ac<b/><i/></p>`, false, emptyList).GetDiffs()
+	assertT.Equal(2, len(diffs))
+	assertT.Equal("Node texts differ: 'a' vs 'ac', segment='before b[0]', path='/p'", diffs[0].DescribeDiff())
+	assertT.Equal("Node texts differ: 'c' vs '', segment='between b[0] and i[1]', path='/p'", diffs[1].DescribeDiff())
+	assertT.Equal(1, diffs[1].(TextDiff).Segment())
+
+	assertT.Equal([]string{"Node texts differ: 'a' vs 'b', segment='after b[0]', path='/p'"},
+		CompareXmlStrings(`<p><b/>a</p>`, `<p><b/>b</p>`, false))
+	assertT.Equal([]string{"Children differ: counts 1 vs 2: i[1]:-1, path='/p'"},
+		CompareXmlStrings(`<p>a<b/></p>`, `<p>a<b/><i/></p>`, false))
+
+	// Texts are aligned by the children edit script
+	assertT.Equal([]string{
+		"Node texts differ: 'a' vs 'ac', segment='before b[0]', path='/p'",
+		"Node texts differ: 'c' vs '', segment='after b[0]', path='/p'",
+		"Children differ: counts 1 vs 2: i[1]:-1, path='/p'",
+	}, CompareXmlStrings(`<p>a<b/>c</p>`, `<p>ac<b/><i/></p>`, false))
+	assertT.Equal([]string{
+		"Children differ: counts 3 vs 2: i[1]:+1, path='/p'",
+	}, CompareXmlStrings(`<p>a<b/>c<i/>d<u/>e</p>`, `<p>a<b/>cd<u/>e</p>`, false))
+	assertT.Equal([]string{
+		"Node texts differ: 'cd' vs 'c d', segment='between b[0] and u[2]', path='/p'",
+		"Children differ: counts 3 vs 2: i[1]:+1, path='/p'",
+	}, CompareXmlStrings(`<p>a<b/>c<i/>d<u/>e</p>`, `<p>a<b/>c d<u/>e</p>`, false))
+	assertT.Equal([]string{"Node texts differ: 'a' vs 'b', segment='around all children', path='/p'"},
+		CompareXmlStrings(`<p>a<b/></p>`, `<p>b<c/></p>`, true))
+
+	// Texts on one side only are still compared
+	assertT.Equal([]string{"Node texts differ: '' vs 'x', segment='after b[0]', path='/p'"},
+		CompareXmlStrings("<p>\n <b/>\n</p>", `<p><b/>x</p>`, false))
+	assertT.True(isBlank([]string{"", ""}))
+	assertT.False(isBlank([]string{"", "x"}))
+}
+
 func TestIgnoringWhitespace(t *testing.T) {
 	assertT := assert.New(t)
 
@@ -84,6 +290,28 @@ func TestIgnoringWhitespace(t *testing.T) {
 	assertT.Equal(emptyList, CompareXmlStrings(xmlSample1, xmlSample2, false))
 }
 
+func TestWhitespaceModes(t *testing.T) {
+	assertT := assert.New(t)
+
+	xmlSample1 := "<a><b>x  y</b><c xml:space=\"preserve\">z </c></a>"
+	xmlSample2 := "<a><b> x y </b><c xml:space=\"preserve\">z</c></a>"
+
+	diffs := CompareXmlStrings(xmlSample1, xmlSample2, false)
+	assertT.Equal([]string{
+		"Node texts differ: 'x  y' vs 'x y', path='/a/b[0]'",
+		"Node texts differ: 'z ' vs 'z', path='/a/c[1]'",
+	}, diffs)
+
+	diffs = ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithWhitespaceMode(CollapseWhitespace)).GetMessages()
+	assertT.Equal([]string{"Node texts differ: 'z ' vs 'z', path='/a/c[1]'"}, diffs)
+
+	diffs = ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithWhitespaceMode(StrictWhitespace)).GetMessages()
+	assertT.Equal("Node texts differ: 'x  y' vs ' x y ', path='/a/b[0]'", diffs[0])
+
+	diffs = ComputeDifferences("<a>x y</a>", "<a>xy</a>", false, emptyList, WithWhitespaceMode(IgnoreWhitespace)).GetMessages()
+	assertT.Equal([]string{}, diffs)
+}
+
 func TestIgnoreComments(t *testing.T) {
 	assertT := assert.New(t)
 
@@ -97,20 +325,34 @@ func TestStoppingOnTheFirstError(t *testing.T) {
 
 	diffs1 := CompareXmlStrings(xmlString1, xmlMixed, true)
 	assertT.Equal(1, len(diffs1))
-	assertT.Equal("Node texts differ: '' vs 'Some text ...\n    \n\tmixed with elements', path='/note'", diffs1[0])
+	assertT.Equal("Node texts differ: '' vs 'Some text ...', segment='before to[0]', path='/note'", diffs1[0])
 
 	diffs2 := CompareXmlStrings(xmlString1, xmlMixed, false)
-	assertT.Equal(3, len(diffs2))
+	assertT.Equal(4, len(diffs2))
 	assertT.Equal(diffs1[0], diffs2[0])
-	assertT.Equal("Node texts differ: 'Tove' vs 'Jani', path='/note/to[0]'", diffs2[1])
-	assertT.Equal("Node texts differ: 'Jani' vs 'Tove', path='/note/from[1]'", diffs2[2])
+	assertT.Equal("Node texts differ: '' vs 'mixed with elements', segment='between to[0] and from[1]', path='/note'", diffs2[1])
+	assertT.Equal("Node texts differ: 'Tove' vs 'Jani', path='/note/to[0]'", diffs2[2])
+	assertT.Equal("Node texts differ: 'Jani' vs 'Tove', path='/note/from[1]'", diffs2[3])
+}
+
+func TestStoppingOnTheFirstAttribute(t *testing.T) {
+	assertT := assert.New(t)
+
+	diffs := ComputeDifferences(`<a x="1" y="1"/>`, `<a x="2" y="2"/>`, true, nil).GetMessages()
+	assertT.Equal([]string{"Attribute values differ: '1' vs '2', path='/a/@x'"}, diffs)
+
+	diffs = ComputeDifferences(`<a/>`, `<a x="2" y="2"/>`, true, nil).GetMessages()
+	assertT.Equal([]string{"Attribute is absent in the first sample: 'x=2', path='/a/@x'"}, diffs)
+
+	diffs = ComputeDifferences(`<a x="1" y="1"/>`, `<a x="2" y="2"/>`, false, nil).GetMessages()
+	assertT.Equal(2, len(diffs))
 }
 
 func TestIgnoreList(t *testing.T) {
 	assertT := assert.New(t)
 
 	diffs := CompareXmlStringsEx(xmlString1, xmlMixed, false, []string{`Node texts differ: '.+' vs '.+'`})
-	assertT.Equal(1, len(diffs))
+	assertT.Equal(2, len(diffs))
 
 	xmlString5 := `<a>Node Content</a>`
 	xmlString6 := `<a>Another Content</a>`
@@ -118,6 +360,81 @@ func TestIgnoreList(t *testing.T) {
 	assertT.Equal(emptyList, diffs)
 }
 
+func TestIgnoreRules(t *testing.T) {
+	assertT := assert.New(t)
+
+	xmlSample1 := `<envelope><header><msg timestamp="1" id="1"/></header><body timestamp="1"><x>1</x></body></envelope>`
+	xmlSample2 := `<envelope><header><msg timestamp="2" id="1"/></header><body timestamp="2"><x>2</x></body></envelope>`
+
+	comparator, err := NewComparator(WithIgnoreRules(IgnoreRule{Path: "/envelope/header/**", Attr: "timestamp"},
+		IgnoreRule{Type: DiffContent, Path: "x"}))
+	assertT.Nil(err)
+	assertT.Equal([]string{"Attribute values differ: '1' vs '2', path='/envelope/body[1]/@timestamp'"},
+		comparator.Compare(xmlSample1, xmlSample2).GetMessages())
+
+	// Rules with attributes match paths of owning elements as well
+	comparator, err = NewComparator(WithIgnoreRules(IgnoreRule{Path: "/envelope/header/*", Attr: "timestamp"},
+		IgnoreRule{Path: "/envelope/body", Attr: "timestamp"}, IgnoreRule{Type: DiffContent, Path: "x"}))
+	assertT.Nil(err)
+	assertT.Equal(emptyList, comparator.Compare(xmlSample1, xmlSample2).GetMessages())
+
+	_, err = NewComparator(WithIgnoreRules(IgnoreRule{Path: "/a"}, IgnoreRule{Value: "["}))
+	assertT.NotNil(err)
+}
+
+func TestExcludedPaths(t *testing.T) {
+	assertT := assert.New(t)
+
+	xmlSample1 := `<envelope><header id="1" ts="1"><uuid>1</uuid></header><body><x>1</x><y/></body></envelope>`
+	xmlSample2 := `<envelope><header id="1" ts="2"><uuid>2</uuid></header><body><y/><x>1</x></body></envelope>`
+
+	comparator, err := NewComparator(WithExcludedPaths("/envelope/header/uuid", "@ts"))
+	assertT.Nil(err)
+	assertT.Equal([]string{"Children order differ for 2 nodes: x[0]->1, path='/envelope/body[1]'"},
+		comparator.Compare(xmlSample1, xmlSample2).GetMessages())
+
+	_, err = NewComparator(WithExcludedPaths("/a/@b/c"))
+	assertT.NotNil(err)
+}
+
+func TestPathsWithExcludedSiblings(t *testing.T) {
+	assertT := assert.New(t)
+
+	xmlSample1 := `<a><ts/><b>1</b><c/><b/></a>`
+	xmlSample2 := `<a><ts/><b>2</b><c/><b/></a>`
+	assertT.Equal([]string{"Node texts differ: '1' vs '2', path='/a/b[1]'"},
+		ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithExcludedPaths("ts")).GetMessages())
+	assertT.Equal([]string{"Node texts differ: '1' vs '2', path='/a/b[1]'"},
+		ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithExcludedPaths("ts"), WithPathStyle(XPathStyle)).GetMessages())
+
+	xmlSample1 = `<a><ts/><b/><ts/><b>1</b></a>`
+	xmlSample2 = `<a><ts/><b/><ts/><b>2</b></a>`
+	assertT.Equal([]string{"Node texts differ: '1' vs '2', path='/a/b[3]'"},
+		ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithExcludedPaths("ts")).GetMessages())
+	assertT.Equal([]string{"Node texts differ: '1' vs '2', path='/a/b[2]'"},
+		ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithExcludedPaths("//ts", "c"), WithPathStyle(XPathStyle)).GetMessages())
+
+	// The only child in the source has no index
+	assertT.Equal([]string{"Node texts differ: '1' vs '2', path='/a/b'"},
+		ComputeDifferences(`<a><b>1</b></a>`, `<a><b>2</b></a>`, false, emptyList, WithExcludedPaths("ts")).GetMessages())
+
+	// Indices of children in messages and edits count excluded siblings
+	diffs := ComputeDifferences(`<a><ts/><b/><c/></a>`, `<a><ts/><b/></a>`, false, emptyList, WithExcludedPaths("ts")).GetDiffs()
+	assertT.Equal("Children differ: counts 2 vs 1: c[2]:+1, path='/a'", diffs[0].DescribeDiff())
+	childDiff, ok := diffs[0].(ChildrenDiff)
+	assertT.True(ok)
+	assertT.Equal([]ChildEdit{{Change: Removed, Name: "c", Index1: 2, Index2: -1, Path1: "/a/c[2]",
+		Position1: Position{Line: 1, Column: 13, Offset: 12}}}, childDiff.Edits())
+
+	diffs = ComputeDifferences(`<a><ts/><b/><c/></a>`, `<a><c/><ts/><b/></a>`, false, emptyList, WithExcludedPaths("ts")).GetDiffs()
+	orderDiff, ok := diffs[0].(OrderDiff)
+	assertT.True(ok)
+	assertT.Equal([]ChildMove{{Name: "b", From: 1, To: 2}}, orderDiff.Moves())
+
+	assertT.Equal([]string{"Node texts differ: 'x' vs 'y', segment='between b[1] and c[2]', path='/a'"},
+		ComputeDifferences(`<a><ts/><b/>x<c/></a>`, `<a><ts/><b/>y<c/></a>`, false, emptyList, WithExcludedPaths("ts")).GetMessages())
+}
+
 func TestCDataComparison(t *testing.T) {
 	assertT := assert.New(t)
 
@@ -138,6 +455,37 @@ func TestCDataComparison(t *testing.T) {
 	</c>
 </a>`
 	assertT.Equal(emptyList, CompareXmlStrings(xmlSample1, xmlSample2, false))
+
+	diffs := ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithDistinctCData(), WithDoctype()).GetMessages()
+	assertT.Equal([]string{
+		"Document types differ: 'a' vs '', path='/'",
+		"Node texts differ: 'text' vs '<![CDATA[text]]>', path='/a/b[0]'",
+	}, diffs)
+}
+
+func TestMarkupComparison(t *testing.T) {
+	assertT := assert.New(t)
+
+	xmlSample1 := `<?xml version="1.0"?><!-- License A --><?xml-stylesheet href="a.xsl"?><a><!-- x --><b/></a>`
+	xmlSample2 := `<?xml version="1.0" encoding="UTF-8"?><!-- License B --><a><b/><!--x--><!-- y --></a>`
+	assertT.Equal(emptyList, CompareXmlStrings(xmlSample1, xmlSample2, false))
+
+	diffs := ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithComments(), WithProcessingInstructions()).GetDiffs()
+	assertT.Equal(3, len(diffs))
+	assertT.Equal(DiffComments, diffs[0].GetType())
+	assertT.Equal("Comments differ: 'License A' vs 'License B', path='/'", diffs[0].DescribeDiff())
+	assertT.Equal(DiffProcInsts, diffs[1].GetType())
+	assertT.Equal("Processing instructions differ: 'xml-stylesheet href=\"a.xsl\"' vs '', path='/'", diffs[1].DescribeDiff())
+	assertT.Equal("Comments differ: '' vs 'y', path='/a'", diffs[2].DescribeDiff())
+
+	// Markup outside of the root is kept apart from the root's own one
+	assertT.Equal([]string{"Comments differ: 'L' vs '', path='/'", "Comments differ: '' vs 'L', path='/a'"},
+		ComputeDifferences(`<!--L--><a/>`, `<a><!--L--></a>`, false, emptyList, WithComments()).GetMessages())
+	assertT.Equal([]string{"Comments differ: 'L' vs '', path='/'", "Processing instructions differ: '' vs 'pi', path='/'"},
+		ComputeDifferences("<a/>\n<!--L-->", "<a/>\n<?pi?>", false, emptyList, WithComments(), WithProcessingInstructions()).GetMessages())
+
+	diffs = ComputeDifferences(xmlSample1, xmlSample2, true, emptyList, WithComments(), WithProcessingInstructions()).GetDiffs()
+	assertT.Equal(1, len(diffs))
 }
 
 func TestDifferentElementsOrder(t *testing.T) {
@@ -145,7 +493,11 @@ func TestDifferentElementsOrder(t *testing.T) {
 
 	xmlSample1 := `<a><b/><c/></a>`
 	xmlSample2 := `<a><c/><b/></a>`
-	assertT.Equal([]string{"Children order differ for 2 nodes, path='/a'"}, CompareXmlStrings(xmlSample1, xmlSample2, false))
+	assertT.Equal([]string{"Children order differ for 2 nodes: b[0]->1, path='/a'"}, CompareXmlStrings(xmlSample1, xmlSample2, false))
+
+	xmlSample3 := `<a><b/><c/><d/><e/></a>`
+	xmlSample4 := `<a><e/><b/><c/><d/></a>`
+	assertT.Equal([]string{"Children order differ for 4 nodes: e[3]->0, path='/a'"}, CompareXmlStrings(xmlSample3, xmlSample4, false))
 }
 
 func TestDifferentElementsOrderByAttributes(t *testing.T) {
@@ -183,7 +535,114 @@ func TestDifferentElementsOrderByAttributes(t *testing.T) {
   </item>
 </items>
 `
-	assertT.Equal([]string{"Children order differ for 4 nodes, path='/items'"}, CompareXmlStrings(xmlSample1, xmlSample2, false))
+	assertT.Equal([]string{"Children order differ for 4 nodes: item[2]->3, path='/items'"}, CompareXmlStrings(xmlSample1, xmlSample2, false))
+}
+
+func TestUnorderedChildren(t *testing.T) {
+	assertT := assert.New(t)
+
+	xmlSample1 := `<a><b><c/><d>1</d><e/></b><f/></a>`
+	xmlSample2 := `<a><f/><b><e/><c/><d>1</d></b></a>`
+	assertT.Equal(emptyList, ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithUnorderedChildren()).GetMessages())
+	assertT.Equal([]string{"Children order differ for 2 nodes: b[0]->1, path='/a'"},
+		ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithUnorderedChildren("/a/b")).GetMessages())
+
+	xmlSample3 := `<a><b><c/><d>1</d><x/><e/><y/></b></a>`
+	xmlSample4 := `<a><b><e/><d>2</d><z/><c/></b></a>`
+	assertT.Equal([]string{"Children differ: counts 5 vs 4: x[2]:+1, y[4]:+1, z[2]:-1, path='/a/b'", "Node texts differ: '1' vs '2', path='/a/b/d[1]'"},
+		ComputeDifferences(xmlSample3, xmlSample4, false, emptyList, WithUnorderedChildren("b")).GetMessages())
+}
+
+func TestUnorderedChildrenBestMatch(t *testing.T) {
+	assertT := assert.New(t)
+
+	xmlSample1 := `<a><item id="1"><v>1</v></item><item id="2"><v>2</v></item></a>`
+	xmlSample2 := `<a><item id="2"><v>3</v></item><item id="1"><v>1</v></item></a>`
+	assertT.Equal([]string{"Node texts differ: '2' vs '3', path1='/a/item[1]/v', path2='/a/item[0]/v'"},
+		ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithUnorderedChildren()).GetMessages())
+
+	// Equivalent values count as equal
+	xmlSample1 = `<a><item id="1.0" v="p"/><item id="2" v="q"/></a>`
+	xmlSample2 = `<a><item id="2.0" v="r"/><item id="1" v="s"/></a>`
+	assertT.Equal([]string{
+		"Attribute values differ: 'p' vs 's', path1='/a/item[0]/@v', path2='/a/item[1]/@v'",
+		"Attribute values differ: 'q' vs 'r', path1='/a/item[1]/@v', path2='/a/item[0]/@v'",
+	}, ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithUnorderedChildren()).GetMessages())
+}
+
+func TestMatchingByKey(t *testing.T) {
+	assertT := assert.New(t)
+
+	xmlSample1 := `<order><item id="1"><q>1</q></item><item id="2"><q>2</q></item><item id="3"><q>3</q></item></order>`
+	xmlSample2 := `<order><item id="1"><q>1</q></item><item id="3"><q>4</q></item><item id="4"><q>4</q></item></order>`
+	assertT.Equal([]string{"Children differ: counts 3 vs 3: item[@id='2'][1]:+1, item[@id='4'][2]:-1, path='/order'",
+		"Node texts differ: '3' vs '4', path1='/order/item[2]/q', path2='/order/item[1]/q'"},
+		ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithMatchKey("/order/item", "@id")).GetMessages())
+	// Pairing by names only
+	assertT.Equal([]string{"Attribute values differ: '2' vs '3', path='/order/item[1]/@id'", "Node texts differ: '2' vs '4', path='/order/item[1]/q'",
+		"Attribute values differ: '3' vs '4', path='/order/item[2]/@id'", "Node texts differ: '3' vs '4', path='/order/item[2]/q'"},
+		ComputeDifferences(xmlSample1, xmlSample2, false, emptyList).GetMessages())
+}
+
+func TestMatchingByCaseInsensitiveKey(t *testing.T) {
+	assertT := assert.New(t)
+
+	xmlSample1 := `<a><i ID="1">a</i><i ID="2">b</i></a>`
+	xmlSample2 := `<a><i id="2">B</i><i id="1">a</i></a>`
+	assertT.Equal([]string{"Node texts differ: 'b' vs 'B', path1='/a/i[1]', path2='/a/i[0]'"},
+		ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithCaseInsensitiveNames(), WithMatchKey("i", "@id"),
+			WithUnorderedChildren()).GetMessages())
+	assertT.Equal([]string{"Node texts differ: 'b' vs 'B', path='/a/i'"},
+		ComputeDifferences(`<a><i ID="2">b</i></a>`, `<a><i id="2">B</i></a>`, false, emptyList, WithCaseInsensitiveNames(),
+			WithMatchKey("i", "@id")).GetMessages())
+}
+
+func TestUnorderedMatchingByKey(t *testing.T) {
+	assertT := assert.New(t)
+
+	xmlSample1 := `<catalog><book><isbn>1</isbn><price>10</price></book><book><isbn>2</isbn><price>20</price></book></catalog>`
+	xmlSample2 := `<catalog><book><isbn>2</isbn><price>10</price></book><book><isbn>3</isbn><price>20</price></book></catalog>`
+	assertT.Equal([]string{"Children differ: counts 2 vs 2: book[isbn='1'][0]:+1, book[isbn='3'][1]:-1, path='/catalog'",
+		"Node texts differ: '20' vs '10', path1='/catalog/book[1]/price[1]', path2='/catalog/book[0]/price[1]'"},
+		ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithUnorderedChildren(), WithMatchKey("book", "isbn")).GetMessages())
+}
+
+func TestReusableComparator(t *testing.T) {
+	assertT := assert.New(t)
+
+	comparator, err := NewComparator(WithStopOnFirst())
+	assertT.Nil(err)
+	assertT.Equal([]string{"Node names differ: 'note' vs 'root', path1='/note', path2='/root'"}, comparator.Compare(xmlString1, xmlString2).GetMessages())
+	assertT.Equal(emptyList, comparator.Compare(xmlString2, xmlString2).GetMessages())
+	assertT.Equal(1, len(comparator.Compare(xmlString1, xmlMixed).GetMessages()))
+
+	comparator, err = NewComparator(WithIgnoredDiscrepancies(`Node texts differ: '' vs`, `'Jani' vs`))
+	assertT.Nil(err)
+	assertT.Equal([]string{"Node texts differ: 'Tove' vs 'Jani', path='/note/to[0]'"}, comparator.Compare(xmlString1, xmlMixed).GetMessages())
+
+	comparator, err = NewComparator(WithNumericTolerance(0.1))
+	assertT.Nil(err)
+	assertT.Equal(emptyList, comparator.Compare("<a>1.0</a>", "<a>1.05</a>").GetMessages())
+
+	comparator, err = NewComparator(WithIgnoredDiscrepancies("["))
+	assertT.Nil(comparator)
+	assertT.NotNil(err)
+}
+
+func TestInvalidOptions(t *testing.T) {
+	assertT := assert.New(t)
+
+	diffs := ComputeDifferences("<a/>", "<a/>", false, emptyList, WithUnorderedChildren("")).GetDiffs()
+	assertT.Equal(1, len(diffs))
+	assertT.Equal(ParseError, diffs[0].GetType())
+	assertT.Equal("Invalid comparison options: invalid pattern #0 '': empty path pattern", diffs[0].DescribeDiff())
+
+	assertT.NotPanics(func() {
+		diffs = ComputeDifferences("<a/>", "<a/>", false, []string{"a", "(", "b", "[z"}).GetDiffs()
+	})
+	assertT.Equal(1, len(diffs))
+	assertT.Equal("Invalid comparison options: invalid pattern #1 '(': error parsing regexp: missing closing ): `(`\n"+
+		"invalid pattern #3 '[z': error parsing regexp: missing closing ]: `[z`", diffs[0].DescribeDiff())
 }
 
 func TestDifferentChildren(t *testing.T) {
@@ -201,9 +660,9 @@ func TestDifferentChildren2(t *testing.T) {
 	// Edits: DELETE 'c', MODIFY 'd', Add 'e'
 	xmlSample1 := `<a><b><c/><d>1</d></b></a>`
 	xmlSample2 := `<a><b><d>2</d><e/></b></a>`
-	assertT.Equal([]string{"Children differ: counts 2 vs 2: c[0]:+1, e[1]:-1, path='/a/b'", "Node texts differ: '1' vs '2', path='/a/b/d[1]'"},
+	assertT.Equal([]string{"Children differ: counts 2 vs 2: c[0]:+1, e[1]:-1, path='/a/b'", "Node texts differ: '1' vs '2', path1='/a/b/d[1]', path2='/a/b/d[0]'"},
 		CompareXmlStrings(xmlSample1, xmlSample2, false))
-	assertT.Equal([]string{"Children differ: counts 2 vs 2: e[1]:+1, c[0]:-1, path='/a/b'", "Node texts differ: '2' vs '1', path='/a/b/d[0]'"},
+	assertT.Equal([]string{"Children differ: counts 2 vs 2: e[1]:+1, c[0]:-1, path='/a/b'", "Node texts differ: '2' vs '1', path1='/a/b/d[0]', path2='/a/b/d[1]'"},
 		CompareXmlStrings(xmlSample2, xmlSample1, false))
 }
 
@@ -212,21 +671,230 @@ func TestComputeDifferences(t *testing.T) {
 
 	recorder := ComputeDifferences(xmlString1, xmlMixed, false, []string{})
 	diffs := recorder.GetDiffs()
-	assertT.Equal(3, len(diffs))
+	assertT.Equal(4, len(diffs))
 
 	assertT.Equal(DiffContent, diffs[0].GetType())
-	assertT.Equal("Node texts differ: '' vs 'Some text ...\n    \n\tmixed with elements', path='/note'", diffs[0].DescribeDiff())
+	assertT.Equal("Node texts differ: '' vs 'Some text ...', segment='before to[0]', path='/note'", diffs[0].DescribeDiff())
 	assertT.Equal(DiffContent, diffs[1].GetType())
-	assertT.Equal("Node texts differ: 'Tove' vs 'Jani', path='/note/to[0]'", diffs[1].DescribeDiff())
+	assertT.Equal("Node texts differ: '' vs 'mixed with elements', segment='between to[0] and from[1]', path='/note'", diffs[1].DescribeDiff())
 	assertT.Equal(DiffContent, diffs[2].GetType())
-	assertT.Equal("Node texts differ: 'Jani' vs 'Tove', path='/note/from[1]'", diffs[2].DescribeDiff())
+	assertT.Equal("Node texts differ: 'Tove' vs 'Jani', path='/note/to[0]'", diffs[2].DescribeDiff())
+	assertT.Equal(DiffContent, diffs[3].GetType())
+	assertT.Equal("Node texts differ: 'Jani' vs 'Tove', path='/note/from[1]'", diffs[3].DescribeDiff())
+}
+
+func TestDiffPaths(t *testing.T) {
+	assertT := assert.New(t)
+
+	diffs := ComputeDifferences(`<a><b/><c>1</c></a>`, `<a><c>2</c></a>`, false, emptyList).GetDiffs()
+	assertT.Equal(2, len(diffs))
+	assertT.Equal("/a", diffs[0].XmlPath1())
+	assertT.Equal("/a", diffs[0].XmlPath2())
+	assertT.Equal("/a/c[1]", diffs[1].XmlPath())
+	assertT.Equal("/a/c[1]", diffs[1].XmlPath1())
+	assertT.Equal("/a/c", diffs[1].XmlPath2())
+
+	loc := diffLocation{xmlPath2: "/a/b"}
+	assertT.Equal("/a/b", loc.XmlPath())
+	assertT.Equal("path='/a/b'", loc.describePaths())
+}
+
+func TestDiffPositions(t *testing.T) {
+	assertT := assert.New(t)
+
+	diffs := ComputeDifferences(xmlString1, xmlMixed, false, emptyList).GetDiffs()
+	assertT.Equal(4, len(diffs))
+	assertT.Equal(Position{Line: 2, Column: 1, Offset: 1}, diffs[0].Position1())
+	assertT.Equal(Position{Line: 2, Column: 1, Offset: 1}, diffs[0].Position2())
+	assertT.Equal(Position{Line: 3, Column: 5, Offset: 24}, diffs[2].Position1())
+	assertT.Equal(Position{Line: 4, Column: 5, Offset: 42}, diffs[2].Position2())
+
+	diffs = ComputeDifferences("<a>\n<b x='1' y='2'/></a>", "<a>\n<b  y='3'/></a>", false, emptyList).GetDiffs()
+	assertT.Equal(2, len(diffs))
+	assertT.Equal(Position{Line: 2, Column: 4, Offset: 7}, diffs[0].Position1())
+	assertT.Equal(Position{Line: 2, Column: 1, Offset: 4}, diffs[0].Position2())
+	assertT.Equal(Position{Line: 2, Column: 10, Offset: 13}, diffs[1].Position1())
+	assertT.Equal(Position{Line: 2, Column: 5, Offset: 8}, diffs[1].Position2())
+
+	diffs = ComputeDifferences("<a>\n<b></a>", "<a/>", false, emptyList).GetDiffs()
+	assertT.Equal(1, len(diffs))
+	assertT.Equal(2, diffs[0].Position1().Line)
+	assertT.Equal(Position{}, diffs[0].Position2())
+}
+
+func TestPathsOfIdenticalSiblings(t *testing.T) {
+	assertT := assert.New(t)
+
+	xmlSample1 := `<a><b>1</b><b>1</b><b>1</b></a>`
+	xmlSample2 := `<a><b>1</b><b>1</b><b>2</b></a>`
+	diffs := CompareXmlStrings(xmlSample1, xmlSample2, false)
+	assertT.Equal([]string{"Node texts differ: '1' vs '2', path='/a/b[2]'"}, diffs)
+}
+
+func TestPathStyles(t *testing.T) {
+	assertT := assert.New(t)
+
+	xmlSample1 := `<a xmlns:c="urn:c"><b/><c:b>1</c:b><b c:x="1"/></a>`
+	xmlSample2 := `<a xmlns:c="urn:c"><b/><c:b>2</c:b><b c:x="2"/></a>`
+
+	diffs := ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithPathStyle(XPathStyle)).GetMessages()
+	assertT.Equal([]string{
+		"Node texts differ: '1' vs '2', path='/a/c:b'",
+		"Attribute values differ: '1' vs '2', namespace='urn:c', path='/a/b[2]/@c:x'",
+	}, diffs)
+
+	diffs = ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithPathStyle(LocalNameXPathStyle)).GetMessages()
+	assertT.Equal([]string{
+		"Node texts differ: '1' vs '2', path='/*[local-name()='a']/*[local-name()='b'][2]'",
+		"Attribute values differ: '1' vs '2', namespace='urn:c', path='/*[local-name()='a']/*[local-name()='b'][3]/@*[local-name()='x']'",
+	}, diffs)
+
+	diffs = ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithPathStyle(XPathStyle),
+		WithIgnoreRules(IgnoreRule{Path: "/a/b/@x"})).GetMessages()
+	assertT.Equal([]string{"Node texts differ: '1' vs '2', path='/a/c:b'"}, diffs)
+}
+
+func TestChildrenEditScript(t *testing.T) {
+	assertT := assert.New(t)
+
+	xmlSample1 := `<a><b/><c>1</c><d/></a>`
+	xmlSample2 := `<a><c>2</c><d/><e/></a>`
+	diffs := ComputeDifferences(xmlSample1, xmlSample2, false, emptyList).GetDiffs()
+	assertT.Equal(2, len(diffs))
+
+	childDiff, ok := diffs[0].(ChildrenDiff)
+	assertT.True(ok)
+	assertT.Equal(3, childDiff.Count1())
+	assertT.Equal(3, childDiff.Count2())
+	assertT.Equal([]ChildEdit{
+		{Change: Removed, Name: "b", Index1: 0, Index2: -1, Path1: "/a/b[0]", Position1: Position{Line: 1, Column: 4, Offset: 3}},
+		{Change: Changed, Name: "c", Index1: 1, Index2: 0, Path1: "/a/c[1]", Path2: "/a/c[0]",
+			Position1: Position{Line: 1, Column: 8, Offset: 7}, Position2: Position{Line: 1, Column: 4, Offset: 3}},
+		{Change: Added, Name: "e", Index1: -1, Index2: 2, Path2: "/a/e[2]", Position2: Position{Line: 1, Column: 16, Offset: 15}},
+	}, childDiff.Edits())
+
+	textDiff, ok := diffs[1].(TextDiff)
+	assertT.True(ok)
+	assertT.Equal("1", textDiff.Value1())
+	assertT.Equal("2", textDiff.Value2())
+
+	diffs = ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithPathStyle(XPathStyle)).GetDiffs()
+	childDiff, ok = diffs[0].(ChildrenDiff)
+	assertT.True(ok)
+	assertT.Equal("/a/b", childDiff.Edits()[0].Path1)
+	assertT.Equal("/a/e", childDiff.Edits()[2].Path2)
+
+	diffs = ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithUnorderedChildren()).GetDiffs()
+	childDiff, ok = diffs[0].(ChildrenDiff)
+	assertT.True(ok)
+	assertT.Equal([]ChildEdit{
+		{Change: Removed, Name: "b", Index1: 0, Index2: -1, Path1: "/a/b[0]", Position1: Position{Line: 1, Column: 4, Offset: 3}},
+		{Change: Changed, Name: "c", Index1: 1, Index2: 0, Path1: "/a/c[1]", Path2: "/a/c[0]",
+			Position1: Position{Line: 1, Column: 8, Offset: 7}, Position2: Position{Line: 1, Column: 4, Offset: 3}},
+		{Change: Added, Name: "e", Index1: -1, Index2: 2, Path2: "/a/e[2]", Position2: Position{Line: 1, Column: 16, Offset: 15}},
+	}, childDiff.Edits())
 }
 
 func TestAreEqualNumbers(t *testing.T) {
 	assertT := assert.New(t)
 
-	assertT.True(areEqualNumbers("0.2", "0.20"))
-	assertT.True(areEqualNumbers("2", "1.9999997"))
-	assertT.False(areEqualNumbers("1.2", "1,2"))
-	assertT.False(areEqualNumbers("2", "abc"))
+	defaultTolerance := NumericTolerance{Relative: defaultNumericTolerance}
+	assertT.True(areEqualNumbers("0.2", "0.20", defaultTolerance))
+	assertT.True(areEqualNumbers("2", "1.9999997", defaultTolerance))
+	assertT.False(areEqualNumbers("1.2", "1,2", defaultTolerance))
+	assertT.False(areEqualNumbers("2", "abc", defaultTolerance))
+	assertT.False(areEqualNumbers("2", "1.99", defaultTolerance))
+	assertT.True(areEqualNumbers("2", "1.99", NumericTolerance{Relative: 0.01}))
+	assertT.True(areEqualNumbers("2", "1.995", NumericTolerance{Absolute: 0.01}))
+	assertT.False(areEqualNumbers("2", "1.98", NumericTolerance{Absolute: 0.01}))
+	assertT.False(areEqualNumbers("16777217", "16777216", NumericTolerance{}))
+	assertT.True(areEqualNumbers("1.50", "1.5", NumericTolerance{}))
+}
+
+func TestNumericAttributes(t *testing.T) {
+	assertT := assert.New(t)
+
+	xmlSample1 := `<r><item amount="1.0" price="10.00" code="01"/></r>`
+	xmlSample2 := `<r><item amount="1" price="10.001" code="1"/></r>`
+
+	diffs := CompareXmlStrings(xmlSample1, xmlSample2, false)
+	assertT.Equal([]string{"Attribute values differ: '10.00' vs '10.001', path='/r/item/@price'"}, diffs)
+
+	diffs = ComputeDifferences(xmlSample1, xmlSample2, true, emptyList, WithPathTolerance("//@price", NumericTolerance{Absolute: 0.01})).GetMessages()
+	assertT.Equal([]string{}, diffs)
+}
+
+func TestReorderedNumericValues(t *testing.T) {
+	assertT := assert.New(t)
+
+	// Equal numbers have equal hashes, so moved children are detected
+	diffs := CompareXmlStrings(`<a><b v="1.0"/><c>2.50</c></a>`, `<a><c>2.5</c><b v="1"/></a>`, false)
+	assertT.Equal([]string{"Children order differ for 2 nodes: b[0]->1, path='/a'"}, diffs)
+
+	diffs = ComputeDifferences(`<a><b v="1.0"/><c/></a>`, `<a><c/><b v="1"/></a>`, false, emptyList, WithNumericMode(DecimalNumbers)).GetMessages()
+	assertT.Equal([]string{"Children order differ for 2 nodes: b[0]->1, path='/a'"}, diffs)
+
+	diffs = ComputeDifferences(`<a><b v="1.0"/><c/></a>`, `<a><c/><b v="1"/></a>`, false, emptyList, WithNumericMode(ScaledDecimalNumbers)).GetMessages()
+	assertT.Equal([]string{"Attribute values differ: '1.0' vs '1', path1='/a/b[0]/@v', path2='/a/b[1]/@v'"}, diffs)
+}
+
+func TestHashedValue(t *testing.T) {
+	assertT := assert.New(t)
+
+	cfg := newConfig()
+	assertT.Equal("abc", cfg.hashedValue("abc"))
+	assertT.Equal(cfg.hashedValue("1"), cfg.hashedValue("1.000"))
+	assertT.Equal(cfg.hashedValue("150"), cfg.hashedValue("1.5e2"))
+
+	cfg.numericMode = ScaledDecimalNumbers
+	assertT.Equal(cfg.hashedValue("1.50"), cfg.hashedValue("1.50"))
+	assertT.NotEqual(cfg.hashedValue("1.5"), cfg.hashedValue("1.50"))
+}
+
+func TestAreEqualDecimals(t *testing.T) {
+	assertT := assert.New(t)
+
+	assertT.True(areEqualDecimals("1.50", "1.5", false))
+	assertT.False(areEqualDecimals("1.50", "1.5", true))
+	assertT.True(areEqualDecimals("1.50", "150e-2", true))
+	assertT.True(areEqualDecimals("12345678901234.56", "12345678901234.560", false))
+	assertT.False(areEqualDecimals("12345678901234.56", "12345678901234.57", false))
+	assertT.False(areEqualDecimals("0.1", "abc", false))
+
+	assertT.Equal(2, decimalScale("1.50"))
+	assertT.Equal(0, decimalScale("15"))
+	assertT.Equal(-1, decimalScale("15e1"))
+	assertT.Equal(3, decimalScale("-1.5E-2"))
+}
+
+func TestDecimalComparison(t *testing.T) {
+	assertT := assert.New(t)
+
+	xmlSample1 := `<invoice><total>12345678901234.56</total><tax>1.50</tax></invoice>`
+	xmlSample2 := `<invoice><total>12345678901234.57</total><tax>1.5</tax></invoice>`
+
+	diffs := CompareXmlStrings(xmlSample1, xmlSample2, false)
+	assertT.Equal([]string{}, diffs)
+
+	diffs = ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithNumericMode(DecimalNumbers)).GetMessages()
+	assertT.Equal([]string{"Node texts differ: '12345678901234.56' vs '12345678901234.57', path='/invoice/total[0]'"}, diffs)
+
+	diffs = ComputeDifferences(xmlSample1, xmlSample2, false, emptyList, WithNumericMode(ScaledDecimalNumbers)).GetMessages()
+	assertT.Equal([]string{
+		"Node texts differ: '12345678901234.56' vs '12345678901234.57', path='/invoice/total[0]'",
+		"Node texts differ: '1.50' vs '1.5', path='/invoice/tax[1]'",
+	}, diffs)
+}
+
+func TestPathTolerances(t *testing.T) {
+	assertT := assert.New(t)
+
+	xmlSample1 := `<r><price>10.00</price><reading>20.001</reading><other>30.01</other></r>`
+	xmlSample2 := `<r><price>10.000001</price><reading>20.002</reading><other>30.02</other></r>`
+
+	diffs := ComputeDifferences(xmlSample1, xmlSample2, false, emptyList,
+		WithPathTolerance("//price", NumericTolerance{}),
+		WithPathTolerance("//reading", NumericTolerance{Absolute: 1e-2}),
+		WithAbsoluteTolerance(0.1)).GetMessages()
+	assertT.Equal([]string{"Node texts differ: '10.00' vs '10.000001', path='/r/price[0]'"}, diffs)
 }